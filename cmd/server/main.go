@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -18,7 +19,7 @@ import (
 	"inteam/internal/cache"
 	"inteam/internal/config"
 	"inteam/internal/db"
-	"inteam/internal/gigachat"
+	"inteam/internal/llm"
 	"inteam/internal/logger"
 	"inteam/internal/metrics"
 	"inteam/internal/repository"
@@ -70,26 +71,38 @@ func main() {
 		zapLogger.Fatal("failed to run migrations", logger.Error(err))
 	}
 
-	httpClient := &http.Client{
-		Timeout: cfg.HTTPClient.ClientTimeout,
-	}
-
 	redisClient := cache.NewRedis(cfg.Redis, zapLogger)
 	minioStorage, err := storage.NewMinio(cfg.Minio, zapLogger)
 	if err != nil {
 		zapLogger.Warn("failed to init minio, continuing without object storage", logger.Error(err))
 	}
 
-	vkClient := vk.NewClient(cfg.VK, httpClient, zapLogger, redisClient)
-	gigachatClient := gigachat.NewClient(cfg.GigaChat, httpClient, zapLogger)
+	vkClient := vk.NewClient(cfg.VK, newHTTPClient(cfg.VK.Timeout, cfg.HTTPClient.ClientTimeout), zapLogger, redisClient)
+	go vkClient.ListenInvalidations(ctx)
+	llmProvider, err := llm.NewProvider(cfg, func(timeout time.Duration) *http.Client {
+		return newHTTPClient(timeout, cfg.HTTPClient.ClientTimeout)
+	}, zapLogger)
+	if err != nil {
+		zapLogger.Fatal("failed to init llm provider", logger.Error(err))
+	}
+	prompts, err := llm.LoadPrompts(cfg.LLM.Prompts)
+	if err != nil {
+		zapLogger.Fatal("invalid prompt templates", logger.Error(err))
+	}
+	zapLogger.Info("prompt templates loaded",
+		logger.String("profile", strings.Join(prompts.IDs(llm.PromptProfile), ",")),
+		logger.String("community", strings.Join(prompts.IDs(llm.PromptCommunity), ",")),
+	)
+	summarizer := llm.NewSummarizer(llmProvider, prompts, zapLogger)
 
 	profileRepo := repository.NewProfileRepository(gormDB)
 	userRepo := repository.NewUserRepository(gormDB)
 
 	jwtManager := auth.NewJWTManager(cfg.Auth)
 
-	profileService := service.NewProfileService(vkClient, gigachatClient, profileRepo, minioStorage, zapLogger)
+	profileService := service.NewProfileService(cfg.Analysis, vkClient, summarizer, profileRepo, minioStorage, zapLogger)
 	authService := service.NewAuthService(userRepo, jwtManager, zapLogger)
+	cacheService := service.NewCacheService(vkClient, zapLogger)
 
 	router := gin.New()
 	router.Use(gin.Recovery())
@@ -99,7 +112,7 @@ func main() {
 		router.GET("/metrics", metrics.MetricsHandler())
 	}
 
-	httpapi.RegisterRoutes(router, cfg, profileService, authService, jwtManager)
+	httpapi.RegisterRoutes(router, cfg, profileService, authService, cacheService, jwtManager, vkClient, summarizer)
 
 	addr := fmt.Sprintf("%s:%d", cfg.HTTP.Host, cfg.HTTP.Port)
 	srv := &http.Server{
@@ -134,3 +147,14 @@ func main() {
 
 	os.Exit(0)
 }
+
+// newHTTPClient returns a client with its own connection pool.
+func newHTTPClient(timeout, fallback time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = fallback
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+}
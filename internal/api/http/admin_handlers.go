@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"inteam/internal/service"
+)
+
+type cacheIDsRequest struct {
+	VKIDs []int64 `json:"vk_ids" binding:"required,min=1,max=1000"`
+}
+
+func purgeCacheHandler(cacheSvc service.CacheService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req cacheIDsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := cacheSvc.Purge(c.Request.Context(), req.VKIDs); err != nil {
+			respondError(c, err, "failed to purge cache")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purged": len(req.VKIDs)})
+	}
+}
+
+func warmCacheHandler(cacheSvc service.CacheService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req cacheIDsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		res, err := cacheSvc.Warm(c.Request.Context(), req.VKIDs)
+		if err != nil {
+			respondError(c, err, "failed to warm cache")
+			return
+		}
+
+		c.JSON(http.StatusOK, res)
+	}
+}
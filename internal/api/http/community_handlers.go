@@ -0,0 +1,74 @@
+package httpapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"inteam/internal/auth"
+	"inteam/internal/service"
+)
+
+// parseCommunityID accepts a community ID as 123, -123, club123, public123 or
+// event123. Only one prefix is stripped, so "club-5" is rejected.
+func parseCommunityID(c *gin.Context) (int64, bool) {
+	raw := c.Param("id")
+	for _, prefix := range []string{"club", "public", "event", "-"} {
+		if rest, ok := strings.CutPrefix(raw, prefix); ok {
+			raw = rest
+			break
+		}
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 63)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid community id"})
+		return 0, false
+	}
+	return int64(id), true
+}
+
+func getCommunityHandler(profileSvc service.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupID, ok := parseCommunityID(c)
+		if !ok {
+			return
+		}
+
+		profile, err := profileSvc.GetProfile(c.Request.Context(), service.CommunityOwnerID(groupID))
+		if err != nil {
+			respondError(c, err, "failed to get community")
+			return
+		}
+		if profile == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "community not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, profile)
+	}
+}
+
+func analyzeCommunityHandler(profileSvc service.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupID, ok := parseCommunityID(c)
+		if !ok {
+			return
+		}
+
+		if _, exists := c.Get(auth.ContextUserIDKey); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		profile, err := profileSvc.AnalyzeCommunity(analysisContext(c), groupID)
+		if err != nil {
+			respondError(c, err, "failed to analyze community")
+			return
+		}
+
+		c.JSON(http.StatusOK, profile)
+	}
+}
@@ -0,0 +1,43 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+
+	"inteam/internal/domain"
+	"inteam/internal/service"
+)
+
+type profileServiceMock struct {
+	service.ProfileService
+	err error
+}
+
+func (m *profileServiceMock) GetProfile(ctx context.Context, vkID int64) (*domain.Profile, error) {
+	return nil, m.err
+}
+
+func TestGetCommunity_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &profileServiceMock{err: &domain.Error{
+		Kind:    domain.ErrNotFound,
+		Code:    "community_not_found",
+		Message: "vk community not found",
+	}}
+	router := gin.New()
+	router.GET("/communities/:id", getCommunityHandler(svc))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/communities/club1", nil))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Equal(t, "community_not_found", body["code"])
+}
@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"inteam/internal/domain"
+)
+
+func respondError(c *gin.Context, err error, fallback string) {
+	status, body := errorResponse(err, fallback)
+	c.JSON(status, body)
+}
+
+// errorResponse maps err to an HTTP status and the error body returned to
+// API clients.
+func errorResponse(err error, fallback string) (int, gin.H) {
+	var derr *domain.Error
+	if !errors.As(err, &derr) {
+		return http.StatusInternalServerError, gin.H{"error": fallback, "code": "internal"}
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(derr, domain.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(derr, domain.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(derr, domain.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(derr, domain.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(derr, domain.ErrUpstream):
+		status = http.StatusBadGateway
+	case errors.Is(derr, domain.ErrTimeout):
+		status = http.StatusGatewayTimeout
+	}
+
+	return status, gin.H{"error": derr.Message, "code": derr.Code}
+}
@@ -0,0 +1,59 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sony/gobreaker"
+
+	"inteam/internal/llm"
+	"inteam/internal/vk"
+)
+
+func vkTokensHandler(vkClient vk.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		states := vkClient.TokenStates()
+
+		available := 0
+		for _, s := range states {
+			if s.Status == vk.TokenActive {
+				available++
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"tokens":    states,
+			"available": available,
+			"total":     len(states),
+		})
+	}
+}
+
+// readyHandler reports the circuit breakers of upstream clients. The service
+// is not ready while any of them is open: analysis requests would fail fast.
+func readyHandler(vkClient vk.Client, summarizer llm.Summarizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		states := map[string]gobreaker.State{
+			"vk_api":          vkClient.BreakerState(),
+			summarizer.Name(): summarizer.BreakerState(),
+		}
+
+		status := http.StatusOK
+		breakers := make(map[string]string, len(states))
+		for name, state := range states {
+			breakers[name] = state.String()
+			if state == gobreaker.StateOpen {
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		ready := "ok"
+		if status != http.StatusOK {
+			ready = "unavailable"
+		}
+		c.JSON(status, gin.H{
+			"status":   ready,
+			"breakers": breakers,
+		})
+	}
+}
@@ -1,12 +1,16 @@
 package httpapi
 
 import (
+	"context"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"inteam/internal/auth"
+	"inteam/internal/config"
+	"inteam/internal/llm"
 	"inteam/internal/service"
 )
 
@@ -20,6 +24,30 @@ func parseVKID(c *gin.Context) (int64, bool) {
 	return vkID, true
 }
 
+// analysisContext returns the request context with the prompt template
+// selected by the "prompt" query parameter, if any.
+func analysisContext(c *gin.Context) context.Context {
+	ctx := c.Request.Context()
+	if id := c.Query("prompt"); id != "" {
+		ctx = llm.WithPrompt(ctx, id)
+	}
+	return ctx
+}
+
+// analysisWriteDeadline lets analysis routes write their response for the
+// whole analysis budget instead of the server-wide write timeout.
+func analysisWriteDeadline(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var deadline time.Time
+		if cfg.Analysis.Timeout > 0 {
+			deadline = time.Now().Add(cfg.Analysis.Timeout + cfg.HTTP.WriteTimeout)
+		}
+		// Fails only for writers without deadline support, e.g. in tests.
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(deadline)
+		c.Next()
+	}
+}
+
 func getProfileHandler(profileSvc service.ProfileService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		vkID, ok := parseVKID(c)
@@ -29,7 +57,7 @@ func getProfileHandler(profileSvc service.ProfileService) gin.HandlerFunc {
 
 		profile, err := profileSvc.GetProfile(c.Request.Context(), vkID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get profile"})
+			respondError(c, err, "failed to get profile")
 			return
 		}
 		if profile == nil {
@@ -53,9 +81,9 @@ func analyzeProfileHandler(profileSvc service.ProfileService) gin.HandlerFunc {
 			return
 		}
 
-		profile, err := profileSvc.AnalyzeProfile(c.Request.Context(), vkID)
+		profile, err := profileSvc.AnalyzeProfile(analysisContext(c), vkID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to analyze profile"})
+			respondError(c, err, "failed to analyze profile")
 			return
 		}
 
@@ -63,3 +91,137 @@ func analyzeProfileHandler(profileSvc service.ProfileService) gin.HandlerFunc {
 	}
 }
 
+// analyzeProfileStreamHandler streams the analysis progress as Server-Sent Events.
+func analyzeProfileStreamHandler(profileSvc service.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		vkID, ok := parseVKID(c)
+		if !ok {
+			return
+		}
+
+		if _, exists := c.Get(auth.ContextUserIDKey); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		ctx := analysisContext(c)
+		if err := profileSvc.CheckProfilePrompt(ctx); err != nil {
+			respondError(c, err, "failed to analyze profile")
+			return
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		send := func(event string, payload any) error {
+			c.SSEvent(event, payload)
+			c.Writer.Flush()
+			return ctx.Err()
+		}
+
+		profile, err := profileSvc.AnalyzeProfileStream(ctx, vkID, func(ev service.AnalysisEvent) error {
+			return send(ev.Type, ev)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			_, body := errorResponse(err, "failed to analyze profile")
+			_ = send("error", body)
+			return
+		}
+
+		_ = send("done", profile)
+	}
+}
+
+type profileRefRequest struct {
+	Profile string `json:"profile" binding:"required"`
+}
+
+func resolveProfileRef(c *gin.Context, profileSvc service.ProfileService, ref string) (int64, bool) {
+	if ref == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing profile", "code": "invalid_profile_ref"})
+		return 0, false
+	}
+
+	vkID, err := profileSvc.ResolveVKID(c.Request.Context(), ref)
+	if err != nil {
+		respondError(c, err, "failed to resolve profile")
+		return 0, false
+	}
+	return vkID, true
+}
+
+func resolveProfileHandler(profileSvc service.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ref := c.Query("profile")
+		vkID, ok := resolveProfileRef(c, profileSvc, ref)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"vk_id":   vkID,
+			"profile": ref,
+		})
+	}
+}
+
+func getProfileByRefHandler(profileSvc service.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		vkID, ok := resolveProfileRef(c, profileSvc, c.Query("profile"))
+		if !ok {
+			return
+		}
+
+		profile, err := profileSvc.GetProfile(c.Request.Context(), vkID)
+		if err != nil {
+			respondError(c, err, "failed to get profile")
+			return
+		}
+		if profile == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "profile not found", "vk_id": vkID})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"vk_id":   vkID,
+			"profile": profile,
+		})
+	}
+}
+
+func analyzeProfileByRefHandler(profileSvc service.ProfileService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req profileRefRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, exists := c.Get(auth.ContextUserIDKey); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		vkID, ok := resolveProfileRef(c, profileSvc, req.Profile)
+		if !ok {
+			return
+		}
+
+		profile, err := profileSvc.AnalyzeProfile(analysisContext(c), vkID)
+		if err != nil {
+			respondError(c, err, "failed to analyze profile")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"vk_id":   vkID,
+			"profile": profile,
+		})
+	}
+}
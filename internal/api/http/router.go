@@ -7,7 +7,9 @@ import (
 
 	"inteam/internal/auth"
 	"inteam/internal/config"
+	"inteam/internal/llm"
 	"inteam/internal/service"
+	"inteam/internal/vk"
 )
 
 func RegisterRoutes(
@@ -15,11 +17,15 @@ func RegisterRoutes(
 	cfg *config.Config,
 	profileSvc service.ProfileService,
 	authSvc service.AuthService,
+	cacheSvc service.CacheService,
 	jwtManager *auth.JWTManager,
+	vkClient vk.Client,
+	summarizer llm.Summarizer,
 ) {
 	router.GET("/healthz", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+	router.GET("/readyz", readyHandler(vkClient, summarizer))
 
 	authGroup := router.Group("/auth")
 	{
@@ -28,12 +34,28 @@ func RegisterRoutes(
 		authGroup.GET("/vk/callback", vkCallbackHandler(cfg.Auth, jwtManager, authSvc))
 	}
 
+	analysisDeadline := analysisWriteDeadline(cfg)
+
 	protected := router.Group("/")
 	protected.Use(auth.JWTMiddleware(jwtManager))
 	{
 		protected.GET("/me", meHandler(authSvc))
+		protected.GET("/profiles", getProfileByRefHandler(profileSvc))
+		protected.GET("/profiles/resolve", resolveProfileHandler(profileSvc))
+		protected.POST("/profiles/analyze", analysisDeadline, analyzeProfileByRefHandler(profileSvc))
 		protected.GET("/profiles/:vk_id", getProfileHandler(profileSvc))
-		protected.POST("/profiles/:vk_id/analyze", analyzeProfileHandler(profileSvc))
+		protected.POST("/profiles/:vk_id/analyze", analysisDeadline, analyzeProfileHandler(profileSvc))
+		protected.POST("/profiles/:vk_id/analyze/stream", analysisDeadline, analyzeProfileStreamHandler(profileSvc))
+		protected.GET("/communities/:id", getCommunityHandler(profileSvc))
+		protected.POST("/communities/:id/analyze", analysisDeadline, analyzeCommunityHandler(profileSvc))
+	}
+
+	admin := router.Group("/admin")
+	admin.Use(auth.JWTMiddleware(jwtManager), auth.RequireAdmin(authSvc.IsAdmin))
+	{
+		admin.POST("/cache/purge", purgeCacheHandler(cacheSvc))
+		admin.POST("/cache/warm", warmCacheHandler(cacheSvc))
+		admin.GET("/vk/tokens", vkTokensHandler(vkClient))
 	}
 
 	router.Static("/static", "./internal/frontend")
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -34,3 +35,27 @@ func JWTMiddleware(jwtManager *JWTManager) gin.HandlerFunc {
 	}
 }
 
+// RequireAdmin must run after JWTMiddleware; isAdmin is looked up on every
+// request so that revoking the flag takes effect immediately.
+func RequireAdmin(isAdmin func(ctx context.Context, userID uint) (bool, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get(ContextUserIDKey)
+		id, isUint := userID.(uint)
+		if !ok || !isUint {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		admin, err := isAdmin(c.Request.Context(), id)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load user"})
+			return
+		}
+		if !admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			return
+		}
+
+		c.Next()
+	}
+}
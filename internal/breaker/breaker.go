@@ -0,0 +1,99 @@
+// Package breaker builds the circuit breakers guarding outbound API clients.
+// Breakers trip only on upstream failures: an error the upstream returns for
+// a bad request ("user not found", 4xx) says nothing about its health.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sony/gobreaker"
+	"go.uber.org/zap"
+
+	"inteam/internal/config"
+	"inteam/internal/metrics"
+)
+
+// StatusError is an HTTP error status returned by an upstream.
+type StatusError struct {
+	Service    string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s error: status=%d", e.Service, e.StatusCode)
+}
+
+// New builds a breaker from cfg. isFailure decides which errors count towards
+// tripping; nil uses IsUpstreamFailure. State changes are logged and exported
+// as metrics.
+func New(name string, cfg config.BreakerConfig, logger *zap.Logger, isFailure func(error) bool) *gobreaker.CircuitBreaker {
+	if isFailure == nil {
+		isFailure = IsUpstreamFailure
+	}
+	threshold := max(cfg.FailureThreshold, 1)
+
+	metrics.BreakerState(name, int(gobreaker.StateClosed))
+
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        name,
+		MaxRequests: cfg.HalfOpenRequests,
+		Interval:    cfg.Interval,
+		Timeout:     cfg.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= threshold
+		},
+		IsSuccessful: func(err error) bool {
+			var ce *callerError
+			return err == nil || errors.As(err, &ce) || !isFailure(err)
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			logger.Warn("circuit breaker state changed",
+				zap.String("name", name),
+				zap.String("from", from.String()),
+				zap.String("to", to.String()),
+			)
+			metrics.BreakerStateChanged(name, from.String(), to.String(), int(to))
+		},
+	})
+}
+
+// callerError marks an error returned after the caller's context was done.
+type callerError struct{ err error }
+
+func (e *callerError) Error() string { return e.err.Error() }
+func (e *callerError) Unwrap() error { return e.err }
+
+// Execute runs fn through cb. An error returned once ctx is done is the
+// caller's own cancellation or deadline and is not counted as a failure.
+func Execute(ctx context.Context, cb *gobreaker.CircuitBreaker, fn func() (interface{}, error)) (interface{}, error) {
+	result, err := cb.Execute(func() (interface{}, error) {
+		result, err := fn()
+		if err != nil && ctx.Err() != nil {
+			return result, &callerError{err: err}
+		}
+		return result, err
+	})
+	var ce *callerError
+	if errors.As(err, &ce) && err == error(ce) {
+		return result, ce.err
+	}
+	return result, err
+}
+
+// IsUpstreamFailure reports whether err indicates the upstream is unhealthy:
+// transport errors, 429 and 5xx. Cancellation by the caller and other HTTP
+// statuses are not failures; use Execute to also ignore the caller's own
+// deadline.
+func IsUpstreamFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	return true
+}
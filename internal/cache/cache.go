@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores opaque values with a TTL. Implementations treat backend
+// failures as misses: a cache must never fail the request it speeds up.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	GetMulti(ctx context.Context, keys []string) map[string][]byte
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	Delete(ctx context.Context, keys ...string)
+	DeletePrefix(ctx context.Context, prefix string)
+}
+
+type tiered struct {
+	local    Cache
+	remote   Cache
+	localTTL time.Duration
+}
+
+// NewTiered puts local in front of remote. Values found only in remote are
+// copied into local for at most localTTL, so replicas converge on remote
+// state within that time. remote may be nil.
+func NewTiered(local, remote Cache, localTTL time.Duration) Cache {
+	if remote == nil {
+		return local
+	}
+	return &tiered{local: local, remote: remote, localTTL: localTTL}
+}
+
+func (t *tiered) Get(ctx context.Context, key string) ([]byte, bool) {
+	if v, ok := t.local.Get(ctx, key); ok {
+		return v, true
+	}
+	v, ok := t.remote.Get(ctx, key)
+	if ok {
+		t.local.Set(ctx, key, v, t.localTTL)
+	}
+	return v, ok
+}
+
+func (t *tiered) GetMulti(ctx context.Context, keys []string) map[string][]byte {
+	found := t.local.GetMulti(ctx, keys)
+	if len(found) == len(keys) {
+		return found
+	}
+
+	missing := make([]string, 0, len(keys)-len(found))
+	for _, key := range keys {
+		if _, ok := found[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	for key, v := range t.remote.GetMulti(ctx, missing) {
+		t.local.Set(ctx, key, v, t.localTTL)
+		found[key] = v
+	}
+	return found
+}
+
+func (t *tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	t.remote.Set(ctx, key, value, ttl)
+	t.local.Set(ctx, key, value, min(ttl, t.localTTL))
+}
+
+func (t *tiered) Delete(ctx context.Context, keys ...string) {
+	t.local.Delete(ctx, keys...)
+	t.remote.Delete(ctx, keys...)
+}
+
+func (t *tiered) DeletePrefix(ctx context.Context, prefix string) {
+	t.local.DeletePrefix(ctx, prefix)
+	t.remote.DeletePrefix(ctx, prefix)
+}
+
+// Local returns the in-memory tier of a cache built by NewTiered, or c itself
+// when it has a single tier.
+func Local(c Cache) Cache {
+	if t, ok := c.(*tiered); ok {
+		return t.local
+	}
+	return c
+}
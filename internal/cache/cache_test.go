@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU_EvictsAndExpires(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	c := NewLRU(2, 0).(*lru)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	_, ok := c.Get(ctx, "a")
+	require.True(t, ok)
+
+	// "b" is the least recently used entry now.
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+	_, ok = c.Get(ctx, "b")
+	require.False(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get(ctx, "a")
+	require.False(t, ok)
+}
+
+func TestTiered_FillsLocalFromRemote(t *testing.T) {
+	ctx := context.Background()
+	local := NewLRU(10, time.Minute)
+	remote := NewLRU(10, 0)
+	c := NewTiered(local, remote, time.Minute)
+
+	remote.Set(ctx, "k", []byte("v"), time.Hour)
+	v, ok := c.Get(ctx, "k")
+	require.True(t, ok)
+	require.Equal(t, "v", string(v))
+
+	_, ok = local.Get(ctx, "k")
+	require.True(t, ok)
+
+	c.Delete(ctx, "k")
+	_, ok = c.Get(ctx, "k")
+	require.False(t, ok)
+
+	c.Set(ctx, "vk:1:users.get", []byte("u"), time.Hour)
+	c.Set(ctx, "vk:1:wall.get:0:10", []byte("w"), time.Hour)
+	c.Set(ctx, "vk:12:users.get", []byte("u"), time.Hour)
+	c.DeletePrefix(ctx, "vk:1:")
+	require.Len(t, c.GetMulti(ctx, []string{"vk:1:users.get", "vk:1:wall.get:0:10", "vk:12:users.get"}), 1)
+	require.Len(t, remote.GetMulti(ctx, []string{"vk:1:users.get", "vk:12:users.get"}), 1)
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const invalidationChannel = "inteam:cache:invalidate"
+
+// Invalidator removes keys by prefix from a tiered cache and tells the other
+// instances to drop the same prefixes from their in-memory tier.
+type Invalidator struct {
+	cache  Cache
+	redis  redis.UniversalClient
+	logger *zap.Logger
+}
+
+// NewInvalidator works without Redis as well; invalidations then stay local
+// to the instance.
+func NewInvalidator(c Cache, redisClient redis.UniversalClient, logger *zap.Logger) *Invalidator {
+	return &Invalidator{cache: c, redis: redisClient, logger: logger}
+}
+
+func (i *Invalidator) Invalidate(ctx context.Context, prefixes ...string) error {
+	for _, prefix := range prefixes {
+		i.cache.DeletePrefix(ctx, prefix)
+	}
+	if i.redis == nil || len(prefixes) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(prefixes)
+	if err != nil {
+		return err
+	}
+	return i.redis.Publish(ctx, invalidationChannel, payload).Err()
+}
+
+// Listen applies invalidations published by other instances to the local
+// tier until ctx is done.
+func (i *Invalidator) Listen(ctx context.Context) {
+	if i.redis == nil {
+		return
+	}
+
+	sub := i.redis.Subscribe(ctx, invalidationChannel)
+	defer sub.Close()
+
+	local := Local(i.cache)
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var prefixes []string
+			if err := json.Unmarshal([]byte(msg.Payload), &prefixes); err != nil {
+				i.logger.Warn("cache: malformed invalidation message", zap.Error(err))
+				continue
+			}
+			for _, prefix := range prefixes {
+				local.DeletePrefix(ctx, prefix)
+			}
+		}
+	}
+}
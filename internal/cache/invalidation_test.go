@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestInvalidator_DropsLocalCopiesOnOtherInstances(t *testing.T) {
+	srv := miniredis.RunT(t)
+	newInstance := func() (Cache, Cache, *Invalidator) {
+		client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+		local := NewLRU(10, time.Minute)
+		c := NewTiered(local, NewRedisCache(client), time.Minute)
+		return c, local, NewInvalidator(c, client, zaptest.NewLogger(t))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, _, first := newInstance()
+	second, secondLocal, secondInvalidator := newInstance()
+	go secondInvalidator.Listen(ctx)
+	require.Eventually(t, func() bool {
+		return srv.PubSubNumSub(invalidationChannel)[invalidationChannel] == 1
+	}, time.Second, time.Millisecond)
+
+	second.Set(ctx, "vk:1:users.get", []byte("u"), time.Hour)
+	second.Set(ctx, "vk:12:users.get", []byte("u"), time.Hour)
+
+	require.NoError(t, first.Invalidate(ctx, "vk:1:"))
+	require.Eventually(t, func() bool {
+		_, ok := secondLocal.Get(ctx, "vk:1:users.get")
+		return !ok
+	}, time.Second, time.Millisecond)
+	_, ok := second.Get(ctx, "vk:1:users.get")
+	require.False(t, ok)
+	_, ok = secondLocal.Get(ctx, "vk:12:users.get")
+	require.True(t, ok)
+}
@@ -34,3 +34,68 @@ func NewRedis(cfg config.RedisConfig, logger *zap.Logger) redis.UniversalClient
 	return client
 }
 
+type redisCache struct {
+	client redis.UniversalClient
+}
+
+// NewRedisCache adapts a Redis client to Cache. It returns nil for a nil
+// client so that the result can be passed to NewTiered directly.
+func NewRedisCache(client redis.UniversalClient) Cache {
+	if client == nil {
+		return nil
+	}
+	return &redisCache{client: client}
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	v, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func (c *redisCache) GetMulti(ctx context.Context, keys []string) map[string][]byte {
+	found := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return found
+	}
+
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return found
+	}
+	for i, v := range vals {
+		if s, ok := v.(string); ok {
+			found[keys[i]] = []byte(s)
+		}
+	}
+	return found
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	_ = c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *redisCache) Delete(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	_ = c.client.Del(ctx, keys...).Err()
+}
+
+func (c *redisCache) DeletePrefix(ctx context.Context, prefix string) {
+	iter := c.client.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 500 {
+			c.Delete(ctx, keys...)
+			keys = keys[:0]
+		}
+	}
+	c.Delete(ctx, keys...)
+}
@@ -12,16 +12,108 @@ type DBConfig struct {
 }
 
 type VKConfig struct {
+	BaseURL           string        `mapstructure:"base_url" yaml:"base_url"`
+	APIVersion        string        `mapstructure:"api_version" yaml:"api_version"`
+	AccessToken       string        `mapstructure:"access_token" yaml:"access_token"`
+	AccessTokens      []string      `mapstructure:"access_tokens" yaml:"access_tokens"`
+	TokenQuarantine   time.Duration `mapstructure:"token_quarantine" yaml:"token_quarantine"`
+	ThrottleThreshold int           `mapstructure:"throttle_threshold" yaml:"throttle_threshold"`
+	Timeout           time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	OperationTimeout  time.Duration `mapstructure:"operation_timeout" yaml:"operation_timeout"`
+	RateLimit         float64       `mapstructure:"rate_limit" yaml:"rate_limit"`
+	RateBurst         int           `mapstructure:"rate_burst" yaml:"rate_burst"`
+	Cache             VKCacheConfig `mapstructure:"cache" yaml:"cache"`
+	Retry             RetryConfig   `mapstructure:"retry" yaml:"retry"`
+	Breaker           BreakerConfig `mapstructure:"breaker" yaml:"breaker"`
+}
+
+// RetryConfig configures retries of transient failures: exponential backoff
+// from BaseDelay up to MaxDelay with the Jitter share (0..1) randomized.
+type RetryConfig struct {
+	MaxAttempts int           `mapstructure:"max_attempts" yaml:"max_attempts"`
+	BaseDelay   time.Duration `mapstructure:"base_delay" yaml:"base_delay"`
+	MaxDelay    time.Duration `mapstructure:"max_delay" yaml:"max_delay"`
+	Jitter      float64       `mapstructure:"jitter" yaml:"jitter"`
+}
+
+// BreakerConfig configures a circuit breaker: it opens after
+// FailureThreshold consecutive upstream failures, stays open for OpenTimeout
+// and then lets HalfOpenRequests probe requests through. Interval resets the
+// failure counts of a closed breaker, zero never resets them.
+type BreakerConfig struct {
+	FailureThreshold uint32        `mapstructure:"failure_threshold" yaml:"failure_threshold"`
+	HalfOpenRequests uint32        `mapstructure:"half_open_requests" yaml:"half_open_requests"`
+	Interval         time.Duration `mapstructure:"interval" yaml:"interval"`
+	OpenTimeout      time.Duration `mapstructure:"open_timeout" yaml:"open_timeout"`
+}
+
+// VKCacheConfig holds per-method TTLs of cached VK responses; a zero TTL
+// disables caching for that method. LocalTTL caps how long an instance keeps
+// its in-memory copy before rereading Redis.
+type VKCacheConfig struct {
+	LocalSize     int           `mapstructure:"local_size" yaml:"local_size"`
+	LocalTTL      time.Duration `mapstructure:"local_ttl" yaml:"local_ttl"`
+	NegativeTTL   time.Duration `mapstructure:"negative_ttl" yaml:"negative_ttl"`
+	Users         time.Duration `mapstructure:"users" yaml:"users"`
+	ScreenNames   time.Duration `mapstructure:"screen_names" yaml:"screen_names"`
+	Wall          time.Duration `mapstructure:"wall" yaml:"wall"`
+	Gifts         time.Duration `mapstructure:"gifts" yaml:"gifts"`
+	Friends       time.Duration `mapstructure:"friends" yaml:"friends"`
+	Comments      time.Duration `mapstructure:"comments" yaml:"comments"`
+	Groups        time.Duration `mapstructure:"groups" yaml:"groups"`
+	Subscriptions time.Duration `mapstructure:"subscriptions" yaml:"subscriptions"`
+	Communities   time.Duration `mapstructure:"communities" yaml:"communities"`
+	Photos        time.Duration `mapstructure:"photos" yaml:"photos"`
+	Mutual        time.Duration `mapstructure:"mutual" yaml:"mutual"`
+	Bundle        time.Duration `mapstructure:"bundle" yaml:"bundle"`
+}
+
+// GigaChatConfig configures the GigaChat API. AuthKey is the base64 encoded
+// "client_id:client_secret" pair exchanged at AuthURL for access tokens of
+// the given Scope; a static Token skips the exchange.
+type GigaChatConfig struct {
 	BaseURL     string        `mapstructure:"base_url" yaml:"base_url"`
-	APIVersion  string        `mapstructure:"api_version" yaml:"api_version"`
-	AccessToken string        `mapstructure:"access_token" yaml:"access_token"`
+	AuthURL     string        `mapstructure:"auth_url" yaml:"auth_url"`
+	AuthKey     string        `mapstructure:"auth_key" yaml:"auth_key"`
+	Scope       string        `mapstructure:"scope" yaml:"scope"`
+	Token       string        `mapstructure:"token" yaml:"token"`
+	Model       string        `mapstructure:"model" yaml:"model"`
+	Temperature float64       `mapstructure:"temperature" yaml:"temperature"`
+	MaxTokens   int           `mapstructure:"max_tokens" yaml:"max_tokens"`
 	Timeout     time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	Retry       RetryConfig   `mapstructure:"retry" yaml:"retry"`
+	Breaker     BreakerConfig `mapstructure:"breaker" yaml:"breaker"`
 }
 
-type GigaChatConfig struct {
-	BaseURL string        `mapstructure:"base_url" yaml:"base_url"`
-	Token   string        `mapstructure:"token" yaml:"token"`
-	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+// LLMConfig selects the summary backend: "gigachat" (configured by
+// GigaChatConfig), "openai" for any OpenAI-compatible chat API or "offline"
+// for deterministic summaries without a model.
+type LLMConfig struct {
+	Provider string        `mapstructure:"provider" yaml:"provider"`
+	OpenAI   OpenAIConfig  `mapstructure:"openai" yaml:"openai"`
+	Prompts  PromptsConfig `mapstructure:"prompts" yaml:"prompts"`
+}
+
+// PromptsConfig selects the prompt templates. Templates in Dir are loaded on
+// top of the embedded ones; Profile and Community are the IDs used when a
+// request does not ask for a specific template.
+type PromptsConfig struct {
+	Dir       string `mapstructure:"dir" yaml:"dir"`
+	Profile   string `mapstructure:"profile" yaml:"profile"`
+	Community string `mapstructure:"community" yaml:"community"`
+}
+
+// OpenAIConfig configures an OpenAI-compatible chat completions API. Local
+// servers such as Ollama or llama.cpp usually need no APIKey.
+type OpenAIConfig struct {
+	BaseURL     string        `mapstructure:"base_url" yaml:"base_url"`
+	APIKey      string        `mapstructure:"api_key" yaml:"api_key"`
+	Model       string        `mapstructure:"model" yaml:"model"`
+	Temperature float64       `mapstructure:"temperature" yaml:"temperature"`
+	MaxTokens   int           `mapstructure:"max_tokens" yaml:"max_tokens"`
+	Timeout     time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	Retry       RetryConfig   `mapstructure:"retry" yaml:"retry"`
+	Breaker     BreakerConfig `mapstructure:"breaker" yaml:"breaker"`
 }
 
 type RedisConfig struct {
@@ -74,6 +166,31 @@ type TelemetryConfig struct {
 	OTLPEndpoint string `mapstructure:"otlp_endpoint" yaml:"otlp_endpoint"`
 }
 
+type AnalysisConfig struct {
+	WallLimit           int `mapstructure:"wall_limit" yaml:"wall_limit"`
+	GiftsLimit          int `mapstructure:"gifts_limit" yaml:"gifts_limit"`
+	FriendsLimit        int `mapstructure:"friends_limit" yaml:"friends_limit"`
+	GroupsLimit         int `mapstructure:"groups_limit" yaml:"groups_limit"`
+	SubscriptionsLimit  int `mapstructure:"subscriptions_limit" yaml:"subscriptions_limit"`
+	CommentPostsLimit   int `mapstructure:"comment_posts_limit" yaml:"comment_posts_limit"`
+	CommentsPerPost     int `mapstructure:"comments_per_post" yaml:"comments_per_post"`
+	PhotosLimit         int `mapstructure:"photos_limit" yaml:"photos_limit"`
+	AlbumsLimit         int `mapstructure:"albums_limit" yaml:"albums_limit"`
+	NetworkFriendsLimit int `mapstructure:"network_friends_limit" yaml:"network_friends_limit"`
+	NetworkReachLimit   int `mapstructure:"network_reach_limit" yaml:"network_reach_limit"`
+
+	// Timeout is the overall budget of one analysis; every stage is further
+	// limited by its own timeout. Zero disables the limit.
+	Timeout          time.Duration `mapstructure:"timeout" yaml:"timeout"`
+	FetchTimeout     time.Duration `mapstructure:"fetch_timeout" yaml:"fetch_timeout"`
+	SummarizeTimeout time.Duration `mapstructure:"summarize_timeout" yaml:"summarize_timeout"`
+	PersistTimeout   time.Duration `mapstructure:"persist_timeout" yaml:"persist_timeout"`
+
+	// InsightsTimeout limits the insights request, which runs next to the
+	// summary; running out of it saves the profile without insights.
+	InsightsTimeout time.Duration `mapstructure:"insights_timeout" yaml:"insights_timeout"`
+}
+
 type MetricsConfig struct {
 	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
 }
@@ -82,6 +199,8 @@ type Config struct {
 	DB         DBConfig         `mapstructure:"db" yaml:"db"`
 	VK         VKConfig         `mapstructure:"vk" yaml:"vk"`
 	GigaChat   GigaChatConfig   `mapstructure:"gigachat" yaml:"gigachat"`
+	LLM        LLMConfig        `mapstructure:"llm" yaml:"llm"`
+	Analysis   AnalysisConfig   `mapstructure:"analysis" yaml:"analysis"`
 	Redis      RedisConfig      `mapstructure:"redis" yaml:"redis"`
 	Minio      MinioConfig      `mapstructure:"minio" yaml:"minio"`
 	Auth       AuthConfig       `mapstructure:"auth" yaml:"auth"`
@@ -124,6 +243,82 @@ func Load(path string) (*Config, error) {
 	v.SetDefault("telemetry.enabled", false)
 	v.SetDefault("telemetry.service_name", "inteam-backend")
 	v.SetDefault("metrics.enabled", true)
+	v.SetDefault("vk.rate_limit", 3)
+	v.SetDefault("vk.rate_burst", 3)
+	v.SetDefault("vk.token_quarantine", "10m")
+	v.SetDefault("vk.throttle_threshold", 3)
+	v.SetDefault("vk.retry.max_attempts", 3)
+	v.SetDefault("vk.retry.base_delay", "500ms")
+	v.SetDefault("vk.retry.max_delay", "10s")
+	v.SetDefault("vk.retry.jitter", 0.5)
+	v.SetDefault("gigachat.retry.max_attempts", 3)
+	v.SetDefault("gigachat.retry.base_delay", "1s")
+	v.SetDefault("gigachat.retry.max_delay", "10s")
+	v.SetDefault("gigachat.retry.jitter", 0.5)
+	v.SetDefault("vk.timeout", "10s")
+	v.SetDefault("vk.operation_timeout", "1m")
+	v.SetDefault("gigachat.timeout", "30s")
+	v.SetDefault("gigachat.base_url", "https://gigachat.devices.sberbank.ru/api/v1")
+	v.SetDefault("gigachat.auth_url", "https://ngw.devices.sberbank.ru:9443/api/v2/oauth")
+	v.SetDefault("gigachat.scope", "GIGACHAT_API_PERS")
+	v.SetDefault("gigachat.model", "GigaChat")
+	v.SetDefault("gigachat.temperature", 0.7)
+	v.SetDefault("gigachat.max_tokens", 512)
+	v.SetDefault("llm.provider", "gigachat")
+	v.SetDefault("llm.prompts.profile", "profile-v2")
+	v.SetDefault("llm.prompts.community", "community-v1")
+	v.SetDefault("llm.openai.base_url", "https://api.openai.com/v1")
+	v.SetDefault("llm.openai.model", "gpt-4o-mini")
+	v.SetDefault("llm.openai.temperature", 0.7)
+	v.SetDefault("llm.openai.max_tokens", 512)
+	v.SetDefault("llm.openai.timeout", "30s")
+	v.SetDefault("llm.openai.retry.max_attempts", 3)
+	v.SetDefault("llm.openai.retry.base_delay", "1s")
+	v.SetDefault("llm.openai.retry.max_delay", "10s")
+	v.SetDefault("llm.openai.retry.jitter", 0.5)
+	v.SetDefault("llm.openai.breaker.failure_threshold", 5)
+	v.SetDefault("llm.openai.breaker.half_open_requests", 5)
+	v.SetDefault("llm.openai.breaker.interval", "30s")
+	v.SetDefault("llm.openai.breaker.open_timeout", "30s")
+	v.SetDefault("vk.breaker.failure_threshold", 5)
+	v.SetDefault("vk.breaker.half_open_requests", 5)
+	v.SetDefault("vk.breaker.interval", "30s")
+	v.SetDefault("vk.breaker.open_timeout", "30s")
+	v.SetDefault("gigachat.breaker.failure_threshold", 5)
+	v.SetDefault("gigachat.breaker.half_open_requests", 5)
+	v.SetDefault("gigachat.breaker.interval", "30s")
+	v.SetDefault("gigachat.breaker.open_timeout", "30s")
+	v.SetDefault("vk.cache.local_size", 10000)
+	v.SetDefault("vk.cache.local_ttl", "1m")
+	v.SetDefault("vk.cache.negative_ttl", "2m")
+	v.SetDefault("vk.cache.users", "10m")
+	v.SetDefault("vk.cache.screen_names", "24h")
+	v.SetDefault("vk.cache.wall", "5m")
+	v.SetDefault("vk.cache.gifts", "30m")
+	v.SetDefault("vk.cache.friends", "30m")
+	v.SetDefault("vk.cache.comments", "5m")
+	v.SetDefault("vk.cache.groups", "1h")
+	v.SetDefault("vk.cache.subscriptions", "1h")
+	v.SetDefault("vk.cache.communities", "1h")
+	v.SetDefault("vk.cache.photos", "30m")
+	v.SetDefault("vk.cache.mutual", "1h")
+	v.SetDefault("vk.cache.bundle", "5m")
+	v.SetDefault("analysis.wall_limit", 1000)
+	v.SetDefault("analysis.gifts_limit", 500)
+	v.SetDefault("analysis.friends_limit", 10000)
+	v.SetDefault("analysis.groups_limit", 1000)
+	v.SetDefault("analysis.subscriptions_limit", 200)
+	v.SetDefault("analysis.comment_posts_limit", 20)
+	v.SetDefault("analysis.comments_per_post", 200)
+	v.SetDefault("analysis.photos_limit", 1000)
+	v.SetDefault("analysis.albums_limit", 100)
+	v.SetDefault("analysis.network_friends_limit", 1000)
+	v.SetDefault("analysis.network_reach_limit", 100)
+	v.SetDefault("analysis.timeout", "90s")
+	v.SetDefault("analysis.fetch_timeout", "50s")
+	v.SetDefault("analysis.summarize_timeout", "40s")
+	v.SetDefault("analysis.insights_timeout", "40s")
+	v.SetDefault("analysis.persist_timeout", "10s")
 
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
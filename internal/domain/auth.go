@@ -7,6 +7,7 @@ type AuthUser struct {
 	Email        string    `gorm:"uniqueIndex;size:255;not null"`
 	PasswordHash string    `gorm:"size:255;not null"`
 	VKID         int64     `gorm:"index"`
+	IsAdmin      bool      `gorm:"not null;default:false"`
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 }
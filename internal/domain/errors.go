@@ -0,0 +1,32 @@
+package domain
+
+import "errors"
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrNotFound     = errors.New("not found")
+	ErrForbidden    = errors.New("forbidden")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUpstream     = errors.New("upstream unavailable")
+	ErrTimeout      = errors.New("deadline exceeded")
+)
+
+// Error carries a machine-readable code for API clients alongside one of the
+// error kinds above.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
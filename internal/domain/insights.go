@@ -0,0 +1,19 @@
+package domain
+
+// Insights is the structured part of a profile analysis, extracted by the
+// language model alongside the prose summary. PromptVersion is the ID of the
+// prompt template the insights were extracted with.
+type Insights struct {
+	Interests          []string
+	Traits             []Trait
+	Topics             []string
+	CommunicationStyle string
+	RedFlags           []string
+	PromptVersion      string
+}
+
+// Trait is a personality trait; Confidence is between 0 and 1.
+type Trait struct {
+	Name       string
+	Confidence float64
+}
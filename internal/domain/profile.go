@@ -3,26 +3,189 @@ package domain
 import "time"
 
 type VKUser struct {
-	ID         int64
-	ScreenName string
-	FirstName  string
-	LastName   string
-	Sex        int
-	BirthDate  string
-	City       string
-	About      string
+	ID               int64
+	ScreenName       string
+	FirstName        string
+	LastName         string
+	Sex              int
+	BirthDate        string
+	City             string
+	About            string
+	FollowersCount   int
+	Counters         VKCounters
+	Online           bool
+	LastSeen         time.Time
+	LastSeenPlatform int
+	Education        VKEducation
+	Universities     []VKUniversity
+	Career           []VKCareer
+	Occupation       VKOccupation
+	Interests        string
+	Activities       string
+	Books            string
+	Movies           string
+	Music            string
+	Relation         int
+	Personal         VKPersonal
+	Site             string
+	Verified         bool
+}
+
+type VKCounters struct {
+	Photos int
+	Videos int
+	Groups int
+	Audios int
+}
+
+type VKEducation struct {
+	University string
+	Faculty    string
+	Graduation int
+}
+
+type VKUniversity struct {
+	Name       string
+	Faculty    string
+	Chair      string
+	Graduation int
+}
+
+type VKCareer struct {
+	GroupID  int64
+	Company  string
+	Position string
+	From     int
+	Until    int
+}
+
+type VKOccupation struct {
+	Type string
+	ID   int64
+	Name string
+}
+
+type VKPersonal struct {
+	Langs     []string
+	Political int
+	LifeMain  int
 }
 
 type WallPost struct {
+	ID          int64
+	OwnerID     int64
+	FromID      int64
+	SignerID    int64
+	Date        time.Time
+	Edited      time.Time
+	Text        string
+	Likes       int
+	Reposts     int
+	Comments    int
+	Views       int
+	PostType    string
+	IsPinned    bool
+	Attachments []Attachment
+	CopyHistory []RepostedPost
+	Geo         *Geo
+}
+
+func (p WallPost) IsRepost() bool {
+	return len(p.CopyHistory) > 0
+}
+
+// RepostedPost is an entry of a repost chain. The last entry is the original
+// post, OwnerID is the wall it was published on.
+type RepostedPost struct {
+	ID          int64
+	OwnerID     int64
+	FromID      int64
+	Date        time.Time
+	Text        string
+	Attachments []Attachment
+}
+
+// Attachment flattens the VK attachment kinds we care about (photo, video,
+// link, audio, poll, doc); fields irrelevant for a given Type stay empty.
+type Attachment struct {
+	Type        string
+	ID          int64
+	OwnerID     int64
+	Title       string
+	Description string
+	Text        string
+	URL         string
+	Artist      string
+	Duration    int
+	Ext         string
+	Size        int64
+	PollAnswers []string
+}
+
+type Geo struct {
+	Type        string
+	Coordinates string
+	Place       string
+	City        string
+}
+
+// Comment is a wall comment; ParentID is the top-level comment of the thread
+// a reply belongs to and is zero for top-level comments.
+type Comment struct {
+	ID             int64
+	OwnerID        int64
+	PostID         int64
+	ParentID       int64
+	FromID         int64
+	Date           time.Time
+	Text           string
+	Likes          int
+	ReplyToUser    int64
+	ReplyToComment int64
+}
+
+type CommenterStat struct {
+	ID       int64
+	Name     string
+	Comments int
+	IsFriend bool
+}
+
+type AudienceMetrics struct {
+	PostsAnalyzed      int
+	CommentsAnalyzed   int
+	UniqueCommenters   int
+	FriendCommentShare float64
+	OwnerReplyRate     float64
+	TopCommenters      []CommenterStat
+}
+
+// Photo holds photo metadata only; Lat and Long are zero when the photo has
+// no place attached.
+type Photo struct {
 	ID       int64
+	AlbumID  int64
+	OwnerID  int64
 	Date     time.Time
 	Text     string
 	Likes    int
-	Reposts  int
 	Comments int
-	Views    int
-	PostType string
-	IsPinned bool
+	Reposts  int
+	Lat      float64
+	Long     float64
+}
+
+func (p Photo) HasPlace() bool {
+	return p.Lat != 0 || p.Long != 0
+}
+
+type Album struct {
+	ID          int64
+	Title       string
+	Description string
+	Size        int
+	Created     time.Time
+	Updated     time.Time
 }
 
 type Gift struct {
@@ -30,38 +193,192 @@ type Gift struct {
 	Text string
 }
 
+// Friend is a friend list entry. Deactivated is "deleted" or "banned" for
+// inactive accounts and empty otherwise.
 type Friend struct {
-	ID        int64
-	FirstName string
-	LastName  string
-	Sex       int
+	ID          int64
+	FirstName   string
+	LastName    string
+	Sex         int
+	City        string
+	BirthDate   string
+	Deactivated string
+	LastSeen    time.Time
+}
+
+func (f Friend) IsDeactivated() bool {
+	return f.Deactivated != ""
+}
+
+// EgoNetwork is the friendship graph among the friends of OwnerID. The owner
+// itself is not part of Edges since it is connected to every node.
+type EgoNetwork struct {
+	OwnerID int64
+	Nodes   []int64
+	Edges   [][2]int64
+}
+
+type NetworkMetrics struct {
+	FriendsAnalyzed       int
+	Edges                 int
+	Density               float64
+	ClusteringCoefficient float64
+	FriendOfFriendReach   int
+	ReachSampled          int
+	DeactivatedShare      float64
+}
+
+type Community struct {
+	ID           int64
+	Name         string
+	ScreenName   string
+	Type         string
+	Activity     string
+	Description  string
+	MembersCount int
+	IsClosed     bool
+	Status       string
+	Site         string
+	Verified     bool
+	City         string
+}
+
+// CommunityVector describes how a community publishes and how its members
+// react. Engagement per member normalizes reactions by audience size so
+// large and small communities can be compared.
+type CommunityVector struct {
+	PostsPerMonth       float64
+	AveragePostLen      float64
+	EngagementRate      float64
+	EngagementPerMember float64
+	ViewsPerPost        float64
+	ReachRate           float64
+	RepostRatio         float64
+	AttachmentsPerPost  float64
+	LastPostAt          time.Time
+	MembersCount        int
+}
+
+type CommunityData struct {
+	Community   Community
+	Wall        []WallPost
+	WallTotal   int
+	Vector      CommunityVector
+	Unavailable []SourceIssue
+	Partial     bool
+}
+
+func (d CommunityData) IsAvailable(source Source) bool {
+	for _, issue := range d.Unavailable {
+		if issue.Source == source {
+			return false
+		}
+	}
+	return true
+}
+
+// InterestShare is the weight of one interest category among the
+// communities a user belongs to or follows.
+type InterestShare struct {
+	Category string
+	Count    int
+	Share    float64
 }
 
 type ActivityVector struct {
 	PostsPerMonth       float64
 	AveragePostLen      float64
 	EngagementRate      float64
+	OriginalPostsCount  int
+	RepostsCount        int
+	RepostRatio         float64
+	OriginalRatio       float64
+	AttachmentsPerPost  float64
 	GiftsCount          int
 	FriendsCount        int
 	ProfileCompleteness float64
+	FollowersCount      int
+	GroupsCount         int
+	PhotosCount         int
+	PhotosPerMonth      float64
+	PhotoLikesAvg       float64
+	GeotaggedPhotoShare float64
+	AlbumsCount         int
+	LastPhotoAt         time.Time
+	MissingSources      []Source
 }
 
 type ProfileData struct {
-	User    VKUser
-	Wall    []WallPost
-	Gifts   []Gift
-	Friends []Friend
-	Vector  ActivityVector
+	User          VKUser
+	Wall          []WallPost
+	Gifts         []Gift
+	Friends       []Friend
+	Groups        []Community
+	Subscriptions []Community
+	Photos        []Photo
+	Albums        []Album
+	WallTotal     int
+	GiftsTotal    int
+	FriendsTotal  int
+	GroupsTotal   int
+	PhotosTotal   int
+	AlbumsTotal   int
+	Interests     []InterestShare
+	Comments      []Comment
+	Audience      AudienceMetrics
+	Network       NetworkMetrics
+	Vector        ActivityVector
+	Unavailable   []SourceIssue
+	Partial       bool
+}
+
+func (d ProfileData) IsAvailable(source Source) bool {
+	for _, issue := range d.Unavailable {
+		if issue.Source == source {
+			return false
+		}
+	}
+	return true
 }
 
+// Profile is a stored analysis. Communities are stored with a negative VKID,
+// following VK's owner_id convention. PromptVersion is the ID of the prompt
+// template the summary was generated with. Insights is nil for communities
+// and for analyses whose template asks for no structured output.
 type Profile struct {
-	ID         uint      `gorm:"primaryKey"`
-	VKID       int64     `gorm:"uniqueIndex;not null"`
-	ScreenName string    `gorm:"size:255"`
-	FullName   string    `gorm:"size:255"`
-	RawJSON    string    `gorm:"type:text"`
-	Summary    string    `gorm:"type:text"`
-	UpdatedAt  time.Time
-	CreatedAt  time.Time
+	ID            uint      `gorm:"primaryKey"`
+	VKID          int64     `gorm:"uniqueIndex;not null"`
+	ScreenName    string    `gorm:"size:255"`
+	FullName      string    `gorm:"size:255"`
+	RawJSON       string    `gorm:"type:text"`
+	Summary       string    `gorm:"type:text"`
+	PromptVersion string    `gorm:"size:64"`
+	Insights      *Insights `gorm:"serializer:json;type:jsonb"`
+	Partial       bool      `gorm:"not null;default:false"`
+	UpdatedAt     time.Time
+	CreatedAt     time.Time
+}
+
+type Source string
+
+const (
+	SourceUser          Source = "user"
+	SourceCommunity     Source = "community"
+	SourceWall          Source = "wall"
+	SourceGifts         Source = "gifts"
+	SourceFriends       Source = "friends"
+	SourceGroups        Source = "groups"
+	SourceSubscriptions Source = "subscriptions"
+	SourceComments      Source = "comments"
+	SourcePhotos        Source = "photos"
+	SourceAlbums        Source = "albums"
+	SourceNetwork       Source = "network"
+)
+
+// SourceIssue records why a data source could not be collected.
+type SourceIssue struct {
+	Source Source
+	Code   string
+	Reason string
 }
 
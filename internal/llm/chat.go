@@ -0,0 +1,333 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/sony/gobreaker"
+	"go.uber.org/zap"
+
+	"inteam/internal/breaker"
+	"inteam/internal/config"
+	"inteam/internal/retry"
+)
+
+var (
+	errNoChoices       = errors.New("llm: completion has no choices")
+	errStreamTruncated = errors.New("llm: stream ended before the completion finished")
+)
+
+// authenticator supplies bearer tokens for a chat API.
+type authenticator interface {
+	Token(ctx context.Context) (string, error)
+	// Invalidate drops a token the API rejected and reports whether another
+	// one can be obtained.
+	Invalidate(token string) bool
+}
+
+// staticToken is a fixed API key; an empty key sends no Authorization
+// header, which is what local servers expect.
+type staticToken string
+
+func (t staticToken) Token(context.Context) (string, error) { return string(t), nil }
+
+func (staticToken) Invalidate(string) bool { return false }
+
+// chatSettings are the generation parameters shared by chat API backends.
+type chatSettings struct {
+	BaseURL     string
+	Model       string
+	Temperature float64
+	MaxTokens   int
+	Retry       config.RetryConfig
+	Breaker     config.BreakerConfig
+	// JSONMode is set for APIs that accept response_format; the others get
+	// JSON requests as plain ones and rely on the prompt.
+	JSONMode bool
+}
+
+type chatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	Temperature float64   `json:"temperature,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message      Message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Model string `json:"model"`
+	Usage Usage  `json:"usage"`
+}
+
+// chatChunk is one event of a streamed completion.
+type chatChunk struct {
+	Choices []struct {
+		Delta        Message `json:"delta"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Model string `json:"model"`
+	Usage *Usage `json:"usage"`
+}
+
+// maxChunkSize bounds a single line of a streamed response.
+const maxChunkSize = 1 << 20
+
+// chatProvider talks to a chat completions API. GigaChat and OpenAI-compatible
+// servers share the request and response format and differ in authentication
+// only.
+type chatProvider struct {
+	name       string
+	settings   chatSettings
+	httpClient *http.Client
+	// streamClient is httpClient without the overall timeout, which would
+	// cut a long stream off; streams are bounded by the context instead.
+	streamClient *http.Client
+	auth         authenticator
+	logger       *zap.Logger
+	retry        retry.Policy
+	cb           *gobreaker.CircuitBreaker
+}
+
+func newChatProvider(name string, settings chatSettings, httpClient *http.Client, auth authenticator, logger *zap.Logger) *chatProvider {
+	streamClient := *httpClient
+	streamClient.Timeout = 0
+
+	return &chatProvider{
+		name:         name,
+		settings:     settings,
+		httpClient:   httpClient,
+		streamClient: &streamClient,
+		auth:         auth,
+		logger:       logger,
+		retry:        retry.FromConfig(settings.Retry),
+		cb:           breaker.New(name, settings.Breaker, logger, nil),
+	}
+}
+
+func (p *chatProvider) Name() string {
+	return p.name
+}
+
+func (p *chatProvider) BreakerState() gobreaker.State {
+	return p.cb.State()
+}
+
+func (p *chatProvider) Complete(ctx context.Context, req Request) (*Completion, error) {
+	body, err := p.requestBody(req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.execute(ctx, func(ctx context.Context) (*Completion, error) {
+		return p.send(ctx, body)
+	})
+}
+
+// Stream may retry only until the first delta is delivered: a partly
+// consumed stream cannot be repeated.
+func (p *chatProvider) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (*Completion, error) {
+	body, err := p.requestBody(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	started := false
+	forward := func(delta string) error {
+		started = true
+		return onDelta(delta)
+	}
+	return p.execute(ctx, func(ctx context.Context) (*Completion, error) {
+		result, err := p.sendStream(ctx, body, forward)
+		if err != nil && started {
+			return nil, retry.Permanent(err)
+		}
+		return result, err
+	})
+}
+
+func (p *chatProvider) requestBody(req Request, stream bool) ([]byte, error) {
+	body := chatRequest{
+		Model:       p.settings.Model,
+		Messages:    req.Messages,
+		Temperature: p.settings.Temperature,
+		MaxTokens:   p.settings.MaxTokens,
+		Stream:      stream,
+	}
+	if req.JSON && p.settings.JSONMode {
+		body.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	return json.Marshal(body)
+}
+
+// execute runs attempt under the retry policy and the circuit breaker.
+func (p *chatProvider) execute(ctx context.Context, attempt func(ctx context.Context) (*Completion, error)) (*Completion, error) {
+	operation := func() (interface{}, error) {
+		var result *Completion
+		err := p.retry.Do(ctx, func(ctx context.Context, n int) error {
+			var err error
+			result, err = attempt(ctx)
+			if retry.IsRetryable(err) {
+				p.logger.Warn("llm request failed, retrying", zap.String("provider", p.name), zap.Int("attempt", n+1), zap.Error(err))
+			}
+			return err
+		})
+		return result, err
+	}
+
+	result, err := breaker.Execute(ctx, p.cb, operation)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Completion), nil
+}
+
+// send performs a single attempt.
+func (p *chatProvider) send(ctx context.Context, body []byte) (*Completion, error) {
+	resp, err := p.post(ctx, p.httpClient, body, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var respBody chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return nil, err
+	}
+	if len(respBody.Choices) == 0 {
+		return nil, errNoChoices
+	}
+
+	choice := respBody.Choices[0]
+	return &Completion{
+		Text:         choice.Message.Content,
+		Model:        respBody.Model,
+		FinishReason: choice.FinishReason,
+		Usage:        respBody.Usage,
+	}, nil
+}
+
+// sendStream performs a single streaming attempt, reading SSE chunks until
+// "data: [DONE]".
+func (p *chatProvider) sendStream(ctx context.Context, body []byte, onDelta DeltaFunc) (*Completion, error) {
+	if _, ok := ctx.Deadline(); !ok && p.httpClient.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.httpClient.Timeout)
+		defer cancel()
+	}
+
+	resp, err := p.post(ctx, p.streamClient, body, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var (
+		result Completion
+		text   strings.Builder
+		done   bool
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxChunkSize)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, err
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			result.FinishReason = choice.FinishReason
+		}
+		if delta := choice.Delta.Content; delta != "" {
+			text.WriteString(delta)
+			if err := onDelta(delta); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, retry.Retryable(err)
+	}
+	if !done && result.FinishReason == "" {
+		return nil, retry.Retryable(errStreamTruncated)
+	}
+
+	result.Text = text.String()
+	return &result, nil
+}
+
+// post sends the chat request and classifies failures for the retry policy.
+func (p *chatProvider) post(ctx context.Context, client *http.Client, body []byte, accept string) (*http.Response, error) {
+	token, err := p.auth.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimRight(p.settings.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", accept)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, retry.Retryable(err)
+	}
+
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	statusErr := &breaker.StatusError{Service: p.name, StatusCode: resp.StatusCode}
+	if resp.StatusCode == http.StatusUnauthorized && p.auth.Invalidate(token) {
+		return nil, retry.After(statusErr, 0)
+	}
+	return nil, retry.StatusError(resp, statusErr)
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"inteam/internal/breaker"
+	"inteam/internal/config"
+	"inteam/internal/retry"
+)
+
+// NewGigaChat returns a provider for the GigaChat API. Unless a static Token
+// is configured, access tokens are obtained with the OAuth client credentials
+// flow.
+func NewGigaChat(cfg config.GigaChatConfig, httpClient *http.Client, logger *zap.Logger) Provider {
+	var auth authenticator = newTokenSource(cfg, httpClient)
+	if cfg.Token != "" {
+		auth = staticToken(cfg.Token)
+	}
+
+	return newChatProvider(ProviderGigaChat, chatSettings{
+		BaseURL:     cfg.BaseURL,
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+		Retry:       cfg.Retry,
+		Breaker:     cfg.Breaker,
+	}, httpClient, auth, logger)
+}
+
+// Access tokens live for 30 minutes; refresh them a bit earlier so that a
+// token does not expire while a completion is in flight.
+const tokenRefreshMargin = time.Minute
+
+var errEmptyToken = errors.New("gigachat: oauth response has no access token")
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	// ExpiresAt is a unix time in milliseconds.
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// tokenSource exchanges the authorization key for an access token using the
+// client credentials flow and caches the token until shortly before it
+// expires. Concurrent callers wait for a single exchange.
+type tokenSource struct {
+	cfg        config.GigaChatConfig
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newTokenSource(cfg config.GigaChatConfig, httpClient *http.Client) *tokenSource {
+	return &tokenSource{
+		cfg:        cfg,
+		httpClient: httpClient,
+		now:        time.Now,
+	}
+}
+
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(tokenRefreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	resp, err := s.exchange(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token = resp.AccessToken
+	s.expiresAt = time.UnixMilli(resp.ExpiresAt)
+	return s.token, nil
+}
+
+// Invalidate drops token if it is still cached, e.g. after GigaChat rejected
+// it before its declared expiry.
+func (s *tokenSource) Invalidate(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == token {
+		s.token = ""
+	}
+	return true
+}
+
+func (s *tokenSource) exchange(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("scope", s.cfg.Scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.AuthURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("RqUID", uuid.NewString())
+	req.Header.Set("Authorization", "Basic "+s.cfg.AuthKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, retry.Retryable(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, retry.StatusError(resp, &breaker.StatusError{Service: "gigachat oauth", StatusCode: resp.StatusCode})
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errEmptyToken
+	}
+	return &token, nil
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+)
+
+// maxInsightsAttempts bounds how many times the model is asked for insights
+// when its answer does not pass validation.
+const maxInsightsAttempts = 3
+
+// maxInsightItems caps each list of the insights; longer lists are cut.
+const maxInsightItems = 10
+
+var ErrInvalidInsights = errors.New("llm: invalid insights")
+
+// insightsRetryPrompt follows an answer that failed validation.
+const insightsRetryPrompt = "Ответ не прошёл проверку: %v. Верни только исправленный JSON-объект той же структуры, без пояснений и разметки."
+
+// insightsDoc is the JSON document the model is asked for. Pointers tell a
+// missing field from an empty one.
+type insightsDoc struct {
+	Interests          *[]string   `json:"interests"`
+	Traits             *[]traitDoc `json:"traits"`
+	Topics             *[]string   `json:"topics"`
+	CommunicationStyle *string     `json:"communication_style"`
+	RedFlags           *[]string   `json:"red_flags"`
+}
+
+type traitDoc struct {
+	Name       string   `json:"name"`
+	Confidence *float64 `json:"confidence"`
+}
+
+// ExtractProfileInsights asks the model for structured insights using the
+// "insights" block of the selected profile template and re-prompts when the
+// answer does not validate. It returns nil insights if the template has no
+// such block.
+func (s *summarizer) ExtractProfileInsights(ctx context.Context, data domain.ProfileData) (*domain.Insights, error) {
+	tracer := otel.Tracer("inteam/llm")
+	ctx, span := tracer.Start(ctx, "ExtractProfileInsights")
+	defer span.End()
+
+	msgs, promptID, err := s.prompts.renderBlock(ctx, PromptProfile, "insights", data)
+	if err != nil || msgs == nil {
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("llm.provider", s.provider.Name()),
+		attribute.String("llm.prompt", promptID),
+	)
+
+	start := time.Now()
+	req := Request{Messages: msgs, JSON: true}
+	for attempt := 1; ; attempt++ {
+		resp, err := s.provider.Complete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		insights, err := parseInsights(resp.Text)
+		if err == nil {
+			insights.PromptVersion = promptID
+			span.SetAttributes(attribute.Int("llm.insights_attempts", attempt))
+			s.logger.Info("llm insights",
+				zap.String("provider", s.provider.Name()),
+				zap.String("prompt", promptID),
+				zap.Duration("latency", time.Since(start)),
+				zap.Int("attempts", attempt),
+				zap.Int("interests", len(insights.Interests)),
+				zap.Int("traits", len(insights.Traits)),
+			)
+			return insights, nil
+		}
+		if attempt == maxInsightsAttempts {
+			return nil, err
+		}
+
+		s.logger.Warn("llm returned invalid insights, asking again",
+			zap.String("provider", s.provider.Name()),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+		req.Messages = append(req.Messages,
+			Message{Role: "assistant", Content: resp.Text},
+			Message{Role: "user", Content: fmt.Sprintf(insightsRetryPrompt, strings.TrimPrefix(err.Error(), ErrInvalidInsights.Error()+": "))},
+		)
+	}
+}
+
+// parseInsights decodes and validates the model's answer. The JSON object
+// may be wrapped in a Markdown code block or surrounded by text.
+func parseInsights(text string) (*domain.Insights, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: %s", ErrInvalidInsights, fmt.Sprintf(format, args...))
+	}
+
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, invalid("no JSON object in the answer")
+	}
+
+	var doc insightsDoc
+	dec := json.NewDecoder(strings.NewReader(text[start : end+1]))
+	if err := dec.Decode(&doc); err != nil {
+		return nil, invalid("malformed JSON: %v", err)
+	}
+
+	switch {
+	case doc.Interests == nil:
+		return nil, invalid("field %q is missing", "interests")
+	case doc.Traits == nil:
+		return nil, invalid("field %q is missing", "traits")
+	case doc.Topics == nil:
+		return nil, invalid("field %q is missing", "topics")
+	case doc.CommunicationStyle == nil:
+		return nil, invalid("field %q is missing", "communication_style")
+	case doc.RedFlags == nil:
+		return nil, invalid("field %q is missing", "red_flags")
+	case strings.TrimSpace(*doc.CommunicationStyle) == "":
+		return nil, invalid("field %q is empty", "communication_style")
+	}
+
+	insights := &domain.Insights{
+		Interests:          cleanList(*doc.Interests),
+		Topics:             cleanList(*doc.Topics),
+		CommunicationStyle: strings.TrimSpace(*doc.CommunicationStyle),
+		RedFlags:           cleanList(*doc.RedFlags),
+	}
+	for i, t := range *doc.Traits {
+		name := strings.TrimSpace(t.Name)
+		switch {
+		case name == "":
+			return nil, invalid("traits[%d]: name is empty", i)
+		case t.Confidence == nil:
+			return nil, invalid("traits[%d]: confidence is missing", i)
+		case *t.Confidence < 0 || *t.Confidence > 1:
+			return nil, invalid("traits[%d]: confidence %v is outside [0, 1]", i, *t.Confidence)
+		}
+		if len(insights.Traits) < maxInsightItems {
+			insights.Traits = append(insights.Traits, domain.Trait{Name: name, Confidence: *t.Confidence})
+		}
+	}
+	return insights, nil
+}
+
+// cleanList trims the items, drops empty ones and duplicates and caps the
+// list at maxInsightItems.
+func cleanList(items []string) []string {
+	list := make([]string, 0, len(items))
+	seen := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		key := strings.ToLower(item)
+		if _, ok := seen[key]; ok || item == "" {
+			continue
+		}
+		if len(list) == maxInsightItems {
+			break
+		}
+		seen[key] = struct{}{}
+		list = append(list, item)
+	}
+	return list
+}
@@ -0,0 +1,76 @@
+// Package llm turns collected profile data into prose summaries. Prompts are
+// built here while a Provider only runs chat completions, so summaries can
+// come from GigaChat, any OpenAI-compatible API (including local servers such
+// as Ollama or llama.cpp) or the deterministic offline backend.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"go.uber.org/zap"
+
+	"inteam/internal/config"
+)
+
+const (
+	ProviderGigaChat = "gigachat"
+	ProviderOpenAI   = "openai"
+	ProviderOffline  = "offline"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type Request struct {
+	Messages []Message
+	// JSON asks for a reply that is a single JSON object.
+	JSON bool
+}
+
+// Usage is the token accounting a provider reports for a completion.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type Completion struct {
+	Text         string
+	Model        string
+	FinishReason string
+	Usage        Usage
+}
+
+// DeltaFunc receives chunks of a streamed completion as they arrive.
+// Returning an error aborts the stream.
+type DeltaFunc func(delta string) error
+
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req Request) (*Completion, error)
+	// Stream is Complete delivering the text through onDelta as it is
+	// generated; the returned Completion holds the whole text.
+	Stream(ctx context.Context, req Request, onDelta DeltaFunc) (*Completion, error)
+	BreakerState() gobreaker.State
+}
+
+// NewProvider builds the provider selected by cfg.LLM.Provider.
+// newHTTPClient returns an HTTP client with the given request timeout.
+func NewProvider(cfg *config.Config, newHTTPClient func(timeout time.Duration) *http.Client, logger *zap.Logger) (Provider, error) {
+	switch cfg.LLM.Provider {
+	case "", ProviderGigaChat:
+		return NewGigaChat(cfg.GigaChat, newHTTPClient(cfg.GigaChat.Timeout), logger), nil
+	case ProviderOpenAI:
+		return NewOpenAI(cfg.LLM.OpenAI, newHTTPClient(cfg.LLM.OpenAI.Timeout), logger), nil
+	case ProviderOffline:
+		return NewOffline(), nil
+	default:
+		return nil, fmt.Errorf("llm: unknown provider %q", cfg.LLM.Provider)
+	}
+}
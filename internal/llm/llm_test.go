@@ -0,0 +1,439 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"inteam/internal/config"
+	"inteam/internal/domain"
+)
+
+type rtFunc func(*http.Request) (*http.Response, error)
+
+func (f rtFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func testPrompts(t *testing.T) *Prompts {
+	t.Helper()
+	prompts, err := LoadPrompts(config.PromptsConfig{Profile: "profile-v1", Community: "community-v1"})
+	require.NoError(t, err)
+	return prompts
+}
+
+func TestGenerateProfileSummary_GigaChatStaticToken(t *testing.T) {
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			require.Equal(t, "/chat/completions", r.URL.Path)
+			require.Equal(t, "Bearer test", r.Header.Get("Authorization"))
+			body := `{"choices":[{"message":{"role":"assistant","content":"summary"},"finish_reason":"stop"}],` +
+				`"model":"GigaChat","usage":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	logger, _ := zap.NewDevelopment()
+	cfg := config.GigaChatConfig{
+		BaseURL: "https://gigachat.example.com",
+		Token:   "test",
+		Model:   "GigaChat",
+	}
+
+	c := NewSummarizer(NewGigaChat(cfg, client, logger), testPrompts(t), logger)
+	summary, err := c.GenerateProfileSummary(context.Background(), domain.ProfileData{})
+	require.NoError(t, err)
+	require.Equal(t, "summary", summary.Text)
+}
+
+func TestGenerate_ExchangesAndRefreshesToken(t *testing.T) {
+	var (
+		exchanges int
+		chats     int
+		request   chatRequest
+	)
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			respond := func(status int, body string) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: status,
+					Body:       io.NopCloser(strings.NewReader(body)),
+					Header:     make(http.Header),
+				}, nil
+			}
+
+			if r.URL.Host == "auth.example.com" {
+				exchanges++
+				require.Equal(t, "Basic a2V5", r.Header.Get("Authorization"))
+				require.NotEmpty(t, r.Header.Get("RqUID"))
+				require.NoError(t, r.ParseForm())
+				require.Equal(t, "GIGACHAT_API_PERS", r.PostForm.Get("scope"))
+				expiresAt := time.Now().Add(30 * time.Minute).UnixMilli()
+				return respond(http.StatusOK, fmt.Sprintf(`{"access_token":"token-%d","expires_at":%d}`, exchanges, expiresAt))
+			}
+
+			chats++
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			// The first token is revoked after the first completion.
+			if chats > 1 && r.Header.Get("Authorization") == "Bearer token-1" {
+				return respond(http.StatusUnauthorized, `{"message":"Token has expired"}`)
+			}
+			return respond(http.StatusOK, `{"choices":[{"message":{"role":"assistant","content":"summary"}}]}`)
+		}),
+	}
+
+	logger, _ := zap.NewDevelopment()
+	cfg := config.GigaChatConfig{
+		BaseURL:     "https://gigachat.example.com/api/v1",
+		AuthURL:     "https://auth.example.com/api/v2/oauth",
+		AuthKey:     "a2V5",
+		Scope:       "GIGACHAT_API_PERS",
+		Model:       "GigaChat-Pro",
+		Temperature: 0.5,
+		MaxTokens:   300,
+		Retry:       config.RetryConfig{MaxAttempts: 2},
+	}
+	c := NewSummarizer(NewGigaChat(cfg, client, logger), testPrompts(t), logger)
+
+	_, err := c.GenerateProfileSummary(context.Background(), domain.ProfileData{})
+	require.NoError(t, err)
+	require.Equal(t, 1, exchanges)
+	require.Equal(t, "GigaChat-Pro", request.Model)
+	require.Equal(t, 300, request.MaxTokens)
+	require.Len(t, request.Messages, 2)
+	require.Equal(t, "system", request.Messages[0].Role)
+	require.Equal(t, "user", request.Messages[1].Role)
+
+	summary, err := c.GenerateProfileSummary(context.Background(), domain.ProfileData{})
+	require.NoError(t, err)
+	require.Equal(t, "summary", summary.Text)
+	require.Equal(t, 2, exchanges)
+	require.Equal(t, 3, chats)
+}
+
+func TestTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	exchanges := 0
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			exchanges++
+			body := fmt.Sprintf(`{"access_token":"token-%d","expires_at":%d}`, exchanges, time.Unix(1800, 0).UnixMilli())
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	s := newTokenSource(config.GigaChatConfig{AuthURL: "https://auth.example.com"}, client)
+	now := time.Unix(0, 0)
+	s.now = func() time.Time { return now }
+
+	token, err := s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+
+	now = time.Unix(1700, 0)
+	token, err = s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-1", token)
+
+	// Within the refresh margin of the expiry.
+	now = time.Unix(1750, 0)
+	token, err = s.Token(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "token-2", token)
+}
+
+func TestOpenAI_LocalServerWithoutKey(t *testing.T) {
+	var request chatRequest
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			require.Equal(t, "http://localhost:11434/v1/chat/completions", r.URL.String())
+			require.Empty(t, r.Header.Get("Authorization"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			body := `{"choices":[{"message":{"role":"assistant","content":"local summary"}}],"model":"llama3"}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	logger, _ := zap.NewDevelopment()
+	p := NewOpenAI(config.OpenAIConfig{BaseURL: "http://localhost:11434/v1/", Model: "llama3"}, client, logger)
+
+	c := NewSummarizer(p, testPrompts(t), logger)
+	summary, err := c.GenerateCommunitySummary(context.Background(), domain.CommunityData{})
+	require.NoError(t, err)
+	require.Equal(t, "local summary", summary.Text)
+	require.Equal(t, "community-v1", summary.PromptVersion)
+	require.Equal(t, "llama3", request.Model)
+	require.Equal(t, ProviderOpenAI, c.Name())
+}
+
+func TestOffline_IsDeterministic(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	c := NewSummarizer(NewOffline(), testPrompts(t), logger)
+	data := domain.ProfileData{
+		User:    domain.VKUser{FirstName: "Test", LastName: "User", City: "Москва"},
+		Partial: true,
+		Unavailable: []domain.SourceIssue{
+			{Source: domain.SourceWall, Code: "profile_private"},
+		},
+	}
+
+	first, err := c.GenerateProfileSummary(context.Background(), data)
+	require.NoError(t, err)
+	second, err := c.GenerateProfileSummary(context.Background(), data)
+	require.NoError(t, err)
+
+	require.Equal(t, first, second)
+	require.Contains(t, first.Text, "Имя: Test User.")
+	require.Contains(t, first.Text, "Город: Москва.")
+	require.NotContains(t, first.Text, "нет данных")
+}
+
+func TestPrompts_SelectsVersionFromDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "profile-v3.tmpl"),
+		[]byte("{{define \"system\"}}Коротко.{{end}}- Имя: {{.User.FirstName}}\n"), 0o644))
+
+	logger, _ := zap.NewDevelopment()
+	prompts, err := LoadPrompts(config.PromptsConfig{Dir: dir, Profile: "profile-v1", Community: "community-v1"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"profile-v1", "profile-v2", "profile-v3"}, prompts.IDs(PromptProfile))
+
+	c := NewSummarizer(NewOffline(), prompts, logger)
+	data := domain.ProfileData{User: domain.VKUser{FirstName: "Test"}}
+
+	summary, err := c.GenerateProfileSummary(context.Background(), data)
+	require.NoError(t, err)
+	require.Equal(t, "profile-v1", summary.PromptVersion)
+
+	ctx := WithPrompt(context.Background(), "profile-v3")
+	require.NoError(t, c.CheckPrompt(ctx, PromptProfile))
+	summary, err = c.GenerateProfileSummary(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, "profile-v3", summary.PromptVersion)
+	require.Contains(t, summary.Text, "Имя: Test.")
+
+	ctx = WithPrompt(context.Background(), "community-v1")
+	require.ErrorIs(t, c.CheckPrompt(ctx, PromptProfile), ErrUnknownPrompt)
+	_, err = c.GenerateProfileSummary(ctx, data)
+	require.ErrorIs(t, err, ErrUnknownPrompt)
+}
+
+func TestLoadPrompts_RejectsInvalidTemplates(t *testing.T) {
+	for name, src := range map[string]string{
+		"profile-v3.tmpl":   "{{if .Partial}}{{.User.Nickname}}{{end}}",
+		"profile-v4.tmpl":   "{{define \"insights\"}}{{.Vector.Mood}}{{end}}{{.User.FirstName}}",
+		"community-v3.tmpl": "{{.Community.Name",
+		"summary-v1.tmpl":   "{{.User.FirstName}}",
+		"profile-v1.tmpl":   "{{.User.FirstName}}",
+		"_profile-v1.tmpl":  "{{define \"profile-v1-facts\"}}{{end}}",
+		"_system.tmpl":      "{{define \"system\"}}{{.User.FirstName}}{{end}}",
+		"profile-v5.tmpl":   "{{range .Groups}}{{if eq .Name \"never\"}}{{.Title}}{{end}}{{end}}{{.User.FirstName}}",
+	} {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644))
+
+		_, err := LoadPrompts(config.PromptsConfig{Dir: dir, Profile: "profile-v1", Community: "community-v1"})
+		require.Error(t, err, name)
+	}
+
+	_, err := LoadPrompts(config.PromptsConfig{Profile: "profile-v9", Community: "community-v1"})
+	require.ErrorIs(t, err, ErrUnknownPrompt)
+}
+
+func TestPrompts_InsightsTemplateKeepsSummaryPrompt(t *testing.T) {
+	prompts := testPrompts(t)
+	for _, data := range sampleProfiles() {
+		v1, _, err := prompts.render(WithPrompt(context.Background(), "profile-v1"), PromptProfile, data)
+		require.NoError(t, err)
+		v2, _, err := prompts.render(WithPrompt(context.Background(), "profile-v2"), PromptProfile, data)
+		require.NoError(t, err)
+		require.Equal(t, v1, v2)
+	}
+}
+
+func TestParseInsights(t *testing.T) {
+	insights, err := parseInsights("```json\n" + `{"interests": ["горы", " Горы ", "", "джаз"],` +
+		`"traits": [{"name": "открытость", "confidence": 0.8}], "topics": [],` +
+		`"communication_style": " дружелюбный ", "red_flags": []}` + "\n```")
+	require.NoError(t, err)
+	require.Equal(t, []string{"горы", "джаз"}, insights.Interests)
+	require.Equal(t, []domain.Trait{{Name: "открытость", Confidence: 0.8}}, insights.Traits)
+	require.Equal(t, "дружелюбный", insights.CommunicationStyle)
+	require.Empty(t, insights.RedFlags)
+
+	for _, text := range []string{
+		"Не могу ответить",
+		`{"interests": "горы"}`,
+		`{"interests": [], "traits": [], "topics": [], "communication_style": "спокойный"}`,
+		`{"interests": [], "traits": [{"name": "смелость", "confidence": 1.5}], "topics": [], "communication_style": "спокойный", "red_flags": []}`,
+		`{"interests": [], "traits": [{"name": "смелость"}], "topics": [], "communication_style": "спокойный", "red_flags": []}`,
+		`{"interests": [], "traits": [], "topics": [], "communication_style": "", "red_flags": []}`,
+	} {
+		_, err := parseInsights(text)
+		require.ErrorIs(t, err, ErrInvalidInsights, text)
+	}
+}
+
+func TestExtractProfileInsights_RepromptsOnInvalidOutput(t *testing.T) {
+	answers := []string{
+		`{"interests": ["горы"]}`,
+		`{"interests": ["горы"], "traits": [{"name": "упорство", "confidence": 0.6}], "topics": ["походы"], ` +
+			`"communication_style": "сдержанный", "red_flags": []}`,
+	}
+	var requests []chatRequest
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			var request chatRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			requests = append(requests, request)
+
+			answer, err := json.Marshal(answers[len(requests)-1])
+			require.NoError(t, err)
+			body := `{"choices":[{"message":{"role":"assistant","content":` + string(answer) + `}}],"model":"gpt-4o-mini"}`
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	logger, _ := zap.NewDevelopment()
+	prompts, err := LoadPrompts(config.PromptsConfig{Profile: "profile-v2", Community: "community-v1"})
+	require.NoError(t, err)
+	c := NewSummarizer(NewOpenAI(config.OpenAIConfig{BaseURL: "https://api.example.com/v1", APIKey: "key"}, client, logger), prompts, logger)
+
+	insights, err := c.ExtractProfileInsights(context.Background(), domain.ProfileData{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"походы"}, insights.Topics)
+	require.Equal(t, "profile-v2", insights.PromptVersion)
+
+	require.Len(t, requests, 2)
+	require.Equal(t, &responseFormat{Type: "json_object"}, requests[0].ResponseFormat)
+	retry := requests[1].Messages
+	require.Len(t, retry, len(requests[0].Messages)+2)
+	require.Equal(t, "assistant", retry[len(retry)-2].Role)
+	require.Contains(t, retry[len(retry)-1].Content, "traits")
+
+	insights, err = c.ExtractProfileInsights(WithPrompt(context.Background(), "profile-v1"), domain.ProfileData{})
+	require.NoError(t, err)
+	require.Nil(t, insights)
+	require.Len(t, requests, 2)
+}
+
+func TestOffline_ReturnsValidInsights(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	prompts, err := LoadPrompts(config.PromptsConfig{Profile: "profile-v2", Community: "community-v1"})
+	require.NoError(t, err)
+	c := NewSummarizer(NewOffline(), prompts, logger)
+
+	insights, err := c.ExtractProfileInsights(context.Background(), domain.ProfileData{
+		User: domain.VKUser{Interests: "горы, джаз"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"горы", "джаз"}, insights.Interests)
+	require.NotEmpty(t, insights.CommunicationStyle)
+}
+
+func TestNewProvider_SelectsBackend(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	newHTTPClient := func(timeout time.Duration) *http.Client { return &http.Client{Timeout: timeout} }
+
+	for name, want := range map[string]string{"": ProviderGigaChat, "openai": ProviderOpenAI, "offline": ProviderOffline} {
+		p, err := NewProvider(&config.Config{LLM: config.LLMConfig{Provider: name}}, newHTTPClient, logger)
+		require.NoError(t, err)
+		require.Equal(t, want, p.Name())
+	}
+
+	_, err := NewProvider(&config.Config{LLM: config.LLMConfig{Provider: "unknown"}}, newHTTPClient, logger)
+	require.Error(t, err)
+}
+
+func TestStream_ParsesServerSentEvents(t *testing.T) {
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			var request chatRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			require.True(t, request.Stream)
+			require.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+
+			body := `data: {"choices":[{"delta":{"role":"assistant","content":"Привет"}}],"model":"GigaChat"}` + "\n\n" +
+				": keep-alive\n\n" +
+				`data: {"choices":[{"delta":{"content":", мир"},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}` + "\n\n" +
+				"data: [DONE]\n\n"
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			}, nil
+		}),
+	}
+
+	logger, _ := zap.NewDevelopment()
+	p := NewGigaChat(config.GigaChatConfig{BaseURL: "https://gigachat.example.com", Token: "test"}, client, logger)
+
+	var deltas []string
+	resp, err := p.Stream(context.Background(), Request{}, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Привет", ", мир"}, deltas)
+	require.Equal(t, "Привет, мир", resp.Text)
+	require.Equal(t, "GigaChat", resp.Model)
+	require.Equal(t, "stop", resp.FinishReason)
+	require.Equal(t, 10, resp.Usage.TotalTokens)
+}
+
+func TestStream_FailsOnTruncatedStream(t *testing.T) {
+	var calls int
+	client := &http.Client{
+		Transport: rtFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+			body := `data: {"choices":[{"delta":{"role":"assistant","content":"Привет"}}],"model":"GigaChat"}` + "\n\n"
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+			}, nil
+		}),
+	}
+
+	logger, _ := zap.NewDevelopment()
+	p := NewGigaChat(config.GigaChatConfig{
+		BaseURL: "https://gigachat.example.com",
+		Token:   "test",
+		Retry:   config.RetryConfig{MaxAttempts: 3},
+	}, client, logger)
+
+	_, err := p.Stream(context.Background(), Request{}, func(string) error { return nil })
+	require.ErrorIs(t, err, errStreamTruncated)
+	require.Equal(t, 1, calls)
+}
+
+func TestTruncate_KeepsRunesWhole(t *testing.T) {
+	require.Equal(t, "при", truncate("привет", 7))
+	require.Equal(t, "привет", truncate("привет", 100))
+	require.Equal(t, "", truncate("привет", 0))
+}
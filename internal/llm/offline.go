@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/sony/gobreaker"
+)
+
+// offlineFacts is how many facts from the prompt make it into an offline
+// summary.
+const offlineFacts = 7
+
+// offlineStyle is the communication style reported by offline insights.
+const offlineStyle = "не определён без языковой модели"
+
+type offlineProvider struct{}
+
+// NewOffline returns a provider that needs no model: the reply is assembled
+// from the facts listed in the prompt, so the same data always yields the
+// same summary. It is meant for development and tests.
+func NewOffline() Provider {
+	return offlineProvider{}
+}
+
+func (offlineProvider) Name() string {
+	return ProviderOffline
+}
+
+func (offlineProvider) BreakerState() gobreaker.State {
+	return gobreaker.StateClosed
+}
+
+func (offlineProvider) Complete(ctx context.Context, req Request) (*Completion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var prompt string
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			prompt = m.Content
+		}
+	}
+
+	if req.JSON {
+		return offlineInsights(prompt)
+	}
+
+	var b strings.Builder
+	b.WriteString("Черновое резюме без языковой модели.")
+	facts := 0
+	for _, line := range strings.Split(prompt, "\n") {
+		fact, ok := strings.CutPrefix(strings.TrimSpace(line), "- ")
+		if !ok || strings.Contains(fact, "нет данных") {
+			continue
+		}
+		b.WriteString(" ")
+		b.WriteString(strings.TrimRight(fact, "."))
+		b.WriteString(".")
+		facts++
+		if facts == offlineFacts {
+			break
+		}
+	}
+
+	text := b.String()
+	words := len(strings.Fields(text))
+	return &Completion{
+		Text:         text,
+		Model:        ProviderOffline,
+		FinishReason: "stop",
+		Usage:        Usage{CompletionTokens: words, TotalTokens: words},
+	}, nil
+}
+
+// offlineInsights answers an insights request: interests come from the
+// "Интересы" line of the prompt, everything else is left empty.
+func offlineInsights(prompt string) (*Completion, error) {
+	interests := []string{}
+	for _, line := range strings.Split(prompt, "\n") {
+		fact, ok := strings.CutPrefix(strings.TrimSpace(line), "- ")
+		if !ok {
+			continue
+		}
+		label, value, ok := strings.Cut(fact, ": ")
+		switch {
+		case !ok || strings.Contains(value, "нет данных"):
+		case label == "Интересы":
+			for _, item := range strings.Split(value, ",") {
+				interests = append(interests, strings.TrimSpace(item))
+			}
+		}
+	}
+
+	text, err := json.Marshal(map[string]any{
+		"interests":           interests,
+		"traits":              []any{},
+		"topics":              []string{},
+		"communication_style": offlineStyle,
+		"red_flags":           []string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Completion{Text: string(text), Model: ProviderOffline, FinishReason: "stop"}, nil
+}
+
+// Stream delivers the offline summary word by word.
+func (p offlineProvider) Stream(ctx context.Context, req Request, onDelta DeltaFunc) (*Completion, error) {
+	result, err := p.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, word := range strings.SplitAfter(result.Text, " ") {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := onDelta(word); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
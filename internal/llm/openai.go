@@ -0,0 +1,23 @@
+package llm
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"inteam/internal/config"
+)
+
+// NewOpenAI returns a provider for an OpenAI-compatible chat completions API:
+// OpenAI itself or a local server such as Ollama or llama.cpp.
+func NewOpenAI(cfg config.OpenAIConfig, httpClient *http.Client, logger *zap.Logger) Provider {
+	return newChatProvider(ProviderOpenAI, chatSettings{
+		BaseURL:     cfg.BaseURL,
+		Model:       cfg.Model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+		Retry:       cfg.Retry,
+		Breaker:     cfg.Breaker,
+		JSONMode:    true,
+	}, httpClient, staticToken(cfg.APIKey), logger)
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"strings"
+
+	"inteam/internal/domain"
+)
+
+// The names and helpers below turn VK codes and lists into the phrases the
+// prompt templates use.
+
+var relationNames = map[int]string{
+	1: "не женат / не замужем",
+	2: "есть друг / подруга",
+	3: "помолвлен(а)",
+	4: "женат / замужем",
+	5: "всё сложно",
+	6: "в активном поиске",
+	7: "влюблён(а)",
+	8: "в гражданском браке",
+}
+
+var politicalNames = map[int]string{
+	1: "коммунистические",
+	2: "социалистические",
+	3: "умеренные",
+	4: "либеральные",
+	5: "консервативные",
+	6: "монархические",
+	7: "ультраконсервативные",
+	8: "индифферентные",
+	9: "либертарианские",
+}
+
+var lifeMainNames = map[int]string{
+	1: "семья и дети",
+	2: "карьера и деньги",
+	3: "развлечения и отдых",
+	4: "наука и исследования",
+	5: "совершенствование мира",
+	6: "саморазвитие",
+	7: "красота и искусство",
+	8: "слава и влияние",
+}
+
+var communityTypeNames = map[string]string{
+	"group": "группа",
+	"page":  "публичная страница",
+	"event": "мероприятие",
+}
+
+func education(u domain.VKUser) string {
+	var study []string
+	if u.Education.University != "" {
+		study = append(study, strings.TrimSpace(u.Education.University+" "+u.Education.Faculty))
+	}
+	for _, uni := range u.Universities {
+		if uni.Name != u.Education.University {
+			study = append(study, strings.TrimSpace(uni.Name+" "+uni.Faculty))
+		}
+	}
+	return strings.Join(study, "; ")
+}
+
+func career(u domain.VKUser) string {
+	var jobs []string
+	for _, job := range u.Career {
+		jobs = append(jobs, strings.TrimSpace(job.Company+" "+job.Position))
+	}
+	if len(jobs) == 0 && u.Occupation.Name != "" {
+		jobs = append(jobs, u.Occupation.Name)
+	}
+	return strings.Join(jobs, "; ")
+}
+
+func albumTitles(albums []domain.Album, n int) string {
+	var titles []string
+	for _, a := range albums {
+		// System albums have negative IDs and generic titles.
+		if a.ID <= 0 || a.Title == "" {
+			continue
+		}
+		if len(titles) == n {
+			break
+		}
+		titles = append(titles, a.Title)
+	}
+	return strings.Join(titles, "; ")
+}
+
+func communityNames(groups, subscriptions []domain.Community, n int) string {
+	var names []string
+	for _, list := range [][]domain.Community{groups, subscriptions} {
+		for _, g := range list {
+			if len(names) == n {
+				return strings.Join(names, "; ")
+			}
+			names = append(names, g.Name)
+		}
+	}
+	return strings.Join(names, "; ")
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"time"
+
+	"inteam/internal/domain"
+)
+
+// Templates are validated by rendering them against these samples: one with
+// every section filled in, one with every source closed and a zero value.
+// text/template only resolves fields on the branches it executes, so the
+// samples have to take each branch at least once.
+
+var sampleDate = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+func sampleProfiles() []domain.ProfileData {
+	full := domain.ProfileData{
+		User: domain.VKUser{
+			ID:             1,
+			ScreenName:     "sample",
+			FirstName:      "Иван",
+			LastName:       "Петров",
+			City:           "Москва",
+			About:          "Люблю походы",
+			FollowersCount: 120,
+			Counters:       domain.VKCounters{Photos: 40, Videos: 3, Groups: 12, Audios: 100},
+			LastSeen:       sampleDate,
+			Education:      domain.VKEducation{University: "МГУ", Faculty: "ВМК"},
+			Universities:   []domain.VKUniversity{{Name: "МФТИ", Faculty: "ФУПМ"}},
+			Career:         []domain.VKCareer{{Company: "Яндекс", Position: "разработчик"}},
+			Occupation:     domain.VKOccupation{Type: "work", Name: "Яндекс"},
+			Interests:      "горы",
+			Activities:     "туризм",
+			Books:          "Толстой",
+			Movies:         "Солярис",
+			Music:          "джаз",
+			Relation:       1,
+			Personal:       domain.VKPersonal{Langs: []string{"Русский", "English"}, Political: 3, LifeMain: 6},
+			Site:           "example.com",
+			Verified:       true,
+		},
+		Wall:      []domain.WallPost{{ID: 1, Text: "Привет"}},
+		WallTotal: 10,
+		Albums:    []domain.Album{{ID: -6, Title: "Фотографии профиля"}, {ID: 7, Title: "Алтай"}},
+		Groups:    []domain.Community{{ID: 1, Name: "Туристы"}},
+		Subscriptions: []domain.Community{
+			{ID: 2, Name: "Джаз"},
+		},
+		Interests: []domain.InterestShare{{Category: "Путешествия", Count: 3, Share: 0.5}},
+		Audience: domain.AudienceMetrics{
+			PostsAnalyzed:      10,
+			CommentsAnalyzed:   25,
+			UniqueCommenters:   8,
+			FriendCommentShare: 0.75,
+			OwnerReplyRate:     0.4,
+		},
+		Network: domain.NetworkMetrics{
+			FriendsAnalyzed:       50,
+			ClusteringCoefficient: 0.31,
+			DeactivatedShare:      0.04,
+			FriendOfFriendReach:   1200,
+			ReachSampled:          20,
+		},
+		Vector: domain.ActivityVector{
+			PostsPerMonth:       2.5,
+			AveragePostLen:      140,
+			EngagementRate:      12.3,
+			OriginalRatio:       0.8,
+			RepostRatio:         0.2,
+			GiftsCount:          4,
+			FriendsCount:        150,
+			GroupsCount:         12,
+			PhotosCount:         40,
+			PhotosPerMonth:      1.2,
+			PhotoLikesAvg:       15,
+			GeotaggedPhotoShare: 0.25,
+			AlbumsCount:         2,
+			LastPhotoAt:         sampleDate,
+		},
+	}
+
+	closed := domain.ProfileData{
+		User:    full.User,
+		Partial: true,
+	}
+	for _, source := range []domain.Source{
+		domain.SourceWall,
+		domain.SourceGifts,
+		domain.SourceFriends,
+		domain.SourceGroups,
+		domain.SourceSubscriptions,
+		domain.SourcePhotos,
+	} {
+		closed.Unavailable = append(closed.Unavailable, domain.SourceIssue{Source: source})
+	}
+
+	return []domain.ProfileData{full, closed, {}}
+}
+
+func sampleCommunities() []domain.CommunityData {
+	full := domain.CommunityData{
+		Community: domain.Community{
+			ID:           1,
+			Name:         "Туристы",
+			Type:         "group",
+			Activity:     "Туризм",
+			Description:  "Походы по России",
+			MembersCount: 5000,
+			IsClosed:     true,
+			Status:       "Идём на Алтай",
+			Site:         "example.com",
+			Verified:     true,
+			City:         "Москва",
+		},
+		Wall:      []domain.WallPost{{ID: 1, Text: "Сбор в субботу"}},
+		WallTotal: 300,
+		Vector: domain.CommunityVector{
+			PostsPerMonth:       12,
+			AveragePostLen:      320,
+			EngagementRate:      40,
+			EngagementPerMember: 0.008,
+			ViewsPerPost:        900,
+			ReachRate:           0.18,
+			RepostRatio:         0.1,
+			LastPostAt:          sampleDate,
+			MembersCount:        5000,
+		},
+	}
+
+	closed := domain.CommunityData{
+		Community:   full.Community,
+		Unavailable: []domain.SourceIssue{{Source: domain.SourceWall}},
+		Partial:     true,
+	}
+
+	return []domain.CommunityData{full, closed, {}}
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sony/gobreaker"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+)
+
+// maxSummaryLen caps the stored summary in bytes.
+const maxSummaryLen = 2000
+
+// Summary is a generated summary along with the ID of the prompt template
+// it was generated with.
+type Summary struct {
+	Text          string
+	PromptVersion string
+}
+
+// Summarizer writes prose summaries of analyzed profiles and communities.
+// The prompt template is the one selected with WithPrompt, or the configured
+// default.
+type Summarizer interface {
+	GenerateProfileSummary(ctx context.Context, data domain.ProfileData) (Summary, error)
+	GenerateCommunitySummary(ctx context.Context, data domain.CommunityData) (Summary, error)
+	// StreamProfileSummary is GenerateProfileSummary delivering the summary
+	// through onDelta while it is generated.
+	StreamProfileSummary(ctx context.Context, data domain.ProfileData, onDelta DeltaFunc) (Summary, error)
+	// ExtractProfileInsights returns structured insights about the profile,
+	// or nil if the selected template does not ask for them.
+	ExtractProfileInsights(ctx context.Context, data domain.ProfileData) (*domain.Insights, error)
+	// CheckPrompt returns ErrUnknownPrompt if the template selected for ctx
+	// does not exist, so callers can reject a request before collecting data.
+	CheckPrompt(ctx context.Context, kind PromptKind) error
+	// Name is the name of the provider behind the summarizer.
+	Name() string
+	BreakerState() gobreaker.State
+}
+
+type summarizer struct {
+	provider Provider
+	prompts  *Prompts
+	logger   *zap.Logger
+}
+
+func NewSummarizer(provider Provider, prompts *Prompts, logger *zap.Logger) Summarizer {
+	return &summarizer{
+		provider: provider,
+		prompts:  prompts,
+		logger:   logger,
+	}
+}
+
+func (s *summarizer) Name() string {
+	return s.provider.Name()
+}
+
+func (s *summarizer) BreakerState() gobreaker.State {
+	return s.provider.BreakerState()
+}
+
+func (s *summarizer) CheckPrompt(ctx context.Context, kind PromptKind) error {
+	_, err := s.prompts.lookup(kind, promptFromContext(ctx))
+	return err
+}
+
+func (s *summarizer) GenerateProfileSummary(ctx context.Context, data domain.ProfileData) (Summary, error) {
+	tracer := otel.Tracer("inteam/llm")
+	ctx, span := tracer.Start(ctx, "GenerateProfileSummary")
+	span.SetAttributes(
+		attribute.Int("wall_posts", len(data.Wall)),
+		attribute.Int("friends", len(data.Friends)),
+		attribute.Int("gifts", len(data.Gifts)),
+	)
+	defer span.End()
+
+	return s.generate(ctx, PromptProfile, data, nil)
+}
+
+func (s *summarizer) StreamProfileSummary(ctx context.Context, data domain.ProfileData, onDelta DeltaFunc) (Summary, error) {
+	tracer := otel.Tracer("inteam/llm")
+	ctx, span := tracer.Start(ctx, "StreamProfileSummary")
+	span.SetAttributes(
+		attribute.Int("wall_posts", len(data.Wall)),
+		attribute.Int("friends", len(data.Friends)),
+		attribute.Int("gifts", len(data.Gifts)),
+	)
+	defer span.End()
+
+	return s.generate(ctx, PromptProfile, data, onDelta)
+}
+
+func (s *summarizer) GenerateCommunitySummary(ctx context.Context, data domain.CommunityData) (Summary, error) {
+	tracer := otel.Tracer("inteam/llm")
+	ctx, span := tracer.Start(ctx, "GenerateCommunitySummary")
+	span.SetAttributes(
+		attribute.Int64("community.id", data.Community.ID),
+		attribute.Int("wall_posts", len(data.Wall)),
+	)
+	defer span.End()
+
+	return s.generate(ctx, PromptCommunity, data, nil)
+}
+
+// generate streams the completion when onDelta is set. Streamed deltas are
+// cut at maxSummaryLen as well, so the client sees what gets stored.
+func (s *summarizer) generate(ctx context.Context, kind PromptKind, data any, onDelta DeltaFunc) (Summary, error) {
+	start := time.Now()
+
+	msgs, promptID, err := s.prompts.render(ctx, kind, data)
+	if err != nil {
+		return Summary{}, err
+	}
+	req := Request{Messages: msgs}
+
+	var resp *Completion
+	if onDelta == nil {
+		resp, err = s.provider.Complete(ctx, req)
+	} else {
+		sent := 0
+		resp, err = s.provider.Stream(ctx, req, func(delta string) error {
+			cut := truncate(delta, maxSummaryLen-sent)
+			sent += len(cut)
+			if len(cut) < len(delta) {
+				sent = maxSummaryLen
+			}
+			if cut == "" {
+				return nil
+			}
+			return onDelta(cut)
+		})
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+
+	text := truncate(resp.Text, maxSummaryLen)
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("llm.provider", s.provider.Name()),
+		attribute.String("llm.model", resp.Model),
+		attribute.String("llm.prompt", promptID),
+		attribute.Int("llm.prompt_tokens", resp.Usage.PromptTokens),
+		attribute.Int("llm.completion_tokens", resp.Usage.CompletionTokens),
+	)
+	s.logger.Info("llm call",
+		zap.String("provider", s.provider.Name()),
+		zap.Duration("latency", time.Since(start)),
+		zap.Int("summary_len", len(text)),
+		zap.String("model", resp.Model),
+		zap.String("prompt", promptID),
+		zap.String("finish_reason", resp.FinishReason),
+		zap.Int("prompt_tokens", resp.Usage.PromptTokens),
+		zap.Int("completion_tokens", resp.Usage.CompletionTokens),
+		zap.Int("total_tokens", resp.Usage.TotalTokens),
+	)
+
+	return Summary{Text: text, PromptVersion: promptID}, nil
+}
+
+// truncate cuts text to at most n bytes without splitting a UTF-8 sequence.
+func truncate(text string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(text) <= n {
+		return text
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return text[:n]
+}
@@ -0,0 +1,301 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"inteam/internal/config"
+	"inteam/internal/domain"
+)
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+const promptExt = ".tmpl"
+
+// partialPrefix marks files with blocks shared by the templates, e.g.
+// "_profile-v1.tmpl". They are parsed into every template and are not
+// prompts themselves.
+const partialPrefix = "_"
+
+var ErrUnknownPrompt = errors.New("llm: unknown prompt template")
+
+// PromptKind is the kind of data a prompt template renders: profile templates
+// get a domain.ProfileData, community templates a domain.CommunityData.
+type PromptKind string
+
+const (
+	PromptProfile   PromptKind = "profile"
+	PromptCommunity PromptKind = "community"
+)
+
+// Prompts holds the prompt templates by version ID. The ID is the file name
+// without the extension and starts with the kind, e.g. "profile-v1". A
+// template may define a "system" block that becomes the system message and
+// may use the blocks defined in partials.
+// Profile templates that define an "insights" block (and optionally
+// "insights_system") are also used to extract structured insights.
+type Prompts struct {
+	templates map[string]*prompt
+	defaults  map[PromptKind]string
+}
+
+type prompt struct {
+	kind PromptKind
+	tmpl *template.Template
+}
+
+// LoadPrompts parses the embedded templates and the ones found in cfg.Dir,
+// which must not reuse an embedded ID, and renders each of them
+// against sample data so that a template referring to a missing field fails
+// at startup rather than on the first analysis.
+func LoadPrompts(cfg config.PromptsConfig) (*Prompts, error) {
+	p := &Prompts{
+		templates: make(map[string]*prompt),
+		defaults: map[PromptKind]string{
+			PromptProfile:   cfg.Profile,
+			PromptCommunity: cfg.Community,
+		},
+	}
+
+	embedded, err := fs.Sub(embeddedPrompts, "prompts")
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string)
+	if err := readPrompts(embedded, sources); err != nil {
+		return nil, err
+	}
+	if cfg.Dir != "" {
+		if err := readPrompts(os.DirFS(cfg.Dir), sources); err != nil {
+			return nil, fmt.Errorf("prompts dir %s: %w", cfg.Dir, err)
+		}
+	}
+	if err := p.parse(sources); err != nil {
+		return nil, err
+	}
+
+	for id, pr := range p.templates {
+		if err := pr.validate(); err != nil {
+			return nil, fmt.Errorf("prompt %s: %w", id, err)
+		}
+	}
+	for kind, id := range p.defaults {
+		if _, err := p.lookup(kind, id); err != nil {
+			return nil, fmt.Errorf("default %s prompt: %w", kind, err)
+		}
+	}
+	return p, nil
+}
+
+// readPrompts adds the template files found in fsys to sources by file name.
+func readPrompts(fsys fs.FS, sources map[string]string) error {
+	files, err := fs.Glob(fsys, "*"+promptExt)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if _, ok := sources[file]; ok {
+			return fmt.Errorf("prompt %s: template %q already exists", file, strings.TrimSuffix(file, promptExt))
+		}
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		sources[file] = string(src)
+	}
+	return nil
+}
+
+func (p *Prompts) parse(sources map[string]string) error {
+	partials := make(map[string]*template.Template)
+	for file, src := range sources {
+		if !strings.HasPrefix(file, partialPrefix) {
+			continue
+		}
+		tmpl, err := template.New(file).Funcs(promptFuncs).Parse(src)
+		if err != nil {
+			return fmt.Errorf("prompt %s: %w", file, err)
+		}
+		partials[file] = tmpl
+	}
+
+	for file, src := range sources {
+		if strings.HasPrefix(file, partialPrefix) {
+			continue
+		}
+		id := strings.TrimSuffix(file, promptExt)
+		kind := PromptKind(id)
+		if i := strings.Index(id, "-"); i > 0 {
+			kind = PromptKind(id[:i])
+		}
+		if kind != PromptProfile && kind != PromptCommunity {
+			return fmt.Errorf("prompt %s: name must start with %q or %q", file, PromptProfile+"-", PromptCommunity+"-")
+		}
+
+		tmpl, err := template.New(id).Funcs(promptFuncs).Parse(src)
+		if err != nil {
+			return fmt.Errorf("prompt %s: %w", file, err)
+		}
+		for name, partial := range partials {
+			for _, block := range partial.Templates() {
+				if block.Name() != name && tmpl.Lookup(block.Name()) != nil {
+					return fmt.Errorf("prompt %s: block %q is also defined in %s", file, block.Name(), name)
+				}
+				if _, err := tmpl.AddParseTree(block.Name(), block.Tree); err != nil {
+					return fmt.Errorf("prompt %s: %w", file, err)
+				}
+			}
+		}
+		p.templates[id] = &prompt{kind: kind, tmpl: tmpl}
+	}
+	return nil
+}
+
+// IDs lists the loaded template IDs of the given kind.
+func (p *Prompts) IDs(kind PromptKind) []string {
+	var ids []string
+	for id, pr := range p.templates {
+		if pr.kind == kind {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// lookup returns the template with the given ID, or the default one of the
+// kind when id is empty, along with the resolved ID.
+func (p *Prompts) lookup(kind PromptKind, id string) (string, error) {
+	if id == "" {
+		id = p.defaults[kind]
+	}
+	pr, ok := p.templates[id]
+	if !ok || pr.kind != kind {
+		return "", fmt.Errorf("%w: %s prompt %q", ErrUnknownPrompt, kind, id)
+	}
+	return id, nil
+}
+
+// render executes the template selected for ctx and returns the messages
+// to send along with the template ID.
+func (p *Prompts) render(ctx context.Context, kind PromptKind, data any) ([]Message, string, error) {
+	return p.renderBlock(ctx, kind, "", data)
+}
+
+// renderBlock is render for a named block and its "<block>_system" companion.
+func (p *Prompts) renderBlock(ctx context.Context, kind PromptKind, block string, data any) ([]Message, string, error) {
+	id, err := p.lookup(kind, promptFromContext(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+	msgs, err := p.templates[id].execute(block, data)
+	if err != nil {
+		return nil, "", fmt.Errorf("prompt %s: %w", id, err)
+	}
+	return msgs, id, nil
+}
+
+// execute renders block, or the template itself when block is empty.
+func (pr *prompt) execute(block string, data any) ([]Message, error) {
+	body, system := pr.tmpl, "system"
+	if block != "" {
+		body, system = pr.tmpl.Lookup(block), block+"_system"
+		if body == nil {
+			return nil, nil
+		}
+	}
+
+	var msgs []Message
+	if tmpl := pr.tmpl.Lookup(system); tmpl != nil {
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, Message{Role: "system", Content: strings.TrimSpace(b.String())})
+	}
+
+	var b bytes.Buffer
+	if err := body.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return append(msgs, Message{Role: "user", Content: strings.TrimSpace(b.String())}), nil
+}
+
+// validate checks field references against the kind's data type, then
+// renders the sample data.
+func (pr *prompt) validate() error {
+	var (
+		root    reflect.Type
+		samples []any
+	)
+	switch pr.kind {
+	case PromptProfile:
+		root = reflect.TypeOf(domain.ProfileData{})
+		for _, data := range sampleProfiles() {
+			samples = append(samples, data)
+		}
+	case PromptCommunity:
+		root = reflect.TypeOf(domain.CommunityData{})
+		for _, data := range sampleCommunities() {
+			samples = append(samples, data)
+		}
+	}
+
+	if err := checkFields(pr.tmpl, root, "", "system", "insights", "insights_system"); err != nil {
+		return err
+	}
+
+	for _, data := range samples {
+		for _, block := range []string{"", "insights"} {
+			msgs, err := pr.execute(block, data)
+			if err != nil {
+				return err
+			}
+			if msgs != nil && msgs[len(msgs)-1].Content == "" {
+				return errors.New("template renders an empty prompt")
+			}
+		}
+	}
+	return nil
+}
+
+type promptKey struct{}
+
+// WithPrompt selects the prompt template, by ID, for summaries generated
+// with the returned context. An empty ID keeps the configured default.
+func WithPrompt(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, promptKey{}, id)
+}
+
+func promptFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(promptKey{}).(string)
+	return id
+}
+
+// promptFuncs are the helpers available to prompt templates on top of the
+// text/template builtins.
+var promptFuncs = template.FuncMap{
+	"max":            func(a, b int) int { return max(a, b) },
+	"join":           strings.Join,
+	"date":           func(t time.Time) string { return t.Format("02.01.2006") },
+	"percent":        func(share float64) float64 { return share * 100 },
+	"relation":       func(v int) string { return relationNames[v] },
+	"political":      func(v int) string { return politicalNames[v] },
+	"lifeMain":       func(v int) string { return lifeMainNames[v] },
+	"communityType":  func(t string) string { return communityTypeNames[t] },
+	"education":      education,
+	"career":         career,
+	"albumTitles":    albumTitles,
+	"communityNames": communityNames,
+}
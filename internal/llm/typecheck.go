@@ -0,0 +1,268 @@
+package llm
+
+import (
+	"fmt"
+	"reflect"
+	"text/template"
+	"text/template/parse"
+)
+
+// fieldChecker resolves template field references against the data type.
+type fieldChecker struct {
+	tmpl *template.Template
+	seen map[string]bool
+}
+
+// checkFields checks the named blocks of tmpl, an empty name being tmpl
+// itself, executed with data of type root.
+func checkFields(tmpl *template.Template, root reflect.Type, blocks ...string) error {
+	c := &fieldChecker{tmpl: tmpl, seen: make(map[string]bool)}
+	for _, block := range blocks {
+		t := tmpl
+		if block != "" {
+			if t = tmpl.Lookup(block); t == nil {
+				continue
+			}
+		}
+		if t.Tree == nil {
+			continue
+		}
+		if err := c.walk(t.Tree, t.Tree.Root, root, map[string]reflect.Type{"$": root}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *fieldChecker) walk(tree *parse.Tree, node parse.Node, dot reflect.Type, vars map[string]reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.walk(tree, child, dot, vars); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		_, err := c.pipe(tree, n.Pipe, dot, vars)
+		return err
+	case *parse.IfNode:
+		inner := copyVars(vars)
+		if _, err := c.pipe(tree, n.Pipe, dot, inner); err != nil {
+			return err
+		}
+		if err := c.walk(tree, n.List, dot, inner); err != nil {
+			return err
+		}
+		return c.walk(tree, n.ElseList, dot, copyVars(vars))
+	case *parse.WithNode:
+		inner := copyVars(vars)
+		t, err := c.pipe(tree, n.Pipe, dot, inner)
+		if err != nil {
+			return err
+		}
+		if err := c.walk(tree, n.List, t, inner); err != nil {
+			return err
+		}
+		return c.walk(tree, n.ElseList, dot, copyVars(vars))
+	case *parse.RangeNode:
+		inner := copyVars(vars)
+		t, err := c.pipe(tree, n.Pipe, dot, inner)
+		if err != nil {
+			return err
+		}
+		key, elem := rangeTypes(t)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			inner[n.Pipe.Decl[0].Ident[0]] = key
+			inner[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		if err := c.walk(tree, n.List, elem, inner); err != nil {
+			return err
+		}
+		return c.walk(tree, n.ElseList, dot, copyVars(vars))
+	case *parse.TemplateNode:
+		var t reflect.Type
+		if n.Pipe != nil {
+			var err error
+			if t, err = c.pipe(tree, n.Pipe, dot, vars); err != nil {
+				return err
+			}
+		}
+		named := c.tmpl.Lookup(n.Name)
+		if named == nil || named.Tree == nil || t == nil {
+			return nil
+		}
+		key := n.Name + "\x00" + t.String()
+		if c.seen[key] {
+			return nil
+		}
+		c.seen[key] = true
+		return c.walk(named.Tree, named.Tree.Root, t, map[string]reflect.Type{"$": t})
+	}
+	return nil
+}
+
+func (c *fieldChecker) pipe(tree *parse.Tree, p *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	var t reflect.Type
+	for _, cmd := range p.Cmds {
+		var err error
+		if t, err = c.command(tree, cmd, dot, vars); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range p.Decl {
+		vars[v.Ident[0]] = t
+	}
+	return t, nil
+}
+
+func (c *fieldChecker) command(tree *parse.Tree, cmd *parse.CommandNode, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	args := make([]reflect.Type, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		if _, ok := arg.(*parse.IdentifierNode); ok && i == 0 {
+			continue
+		}
+		t, err := c.arg(tree, arg, dot, vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = t
+	}
+
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	if !ok {
+		return args[0], nil
+	}
+	return funcResult(ident.Ident, args[1:]), nil
+}
+
+func (c *fieldChecker) arg(tree *parse.Tree, node parse.Node, dot reflect.Type, vars map[string]reflect.Type) (reflect.Type, error) {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fields(tree, n, dot, n.Ident)
+	case *parse.VariableNode:
+		return c.fields(tree, n, vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		t, err := c.arg(tree, n.Node, dot, vars)
+		if err != nil {
+			return nil, err
+		}
+		return c.fields(tree, n, t, n.Field)
+	case *parse.PipeNode:
+		return c.pipe(tree, n, dot, copyVars(vars))
+	case *parse.StringNode:
+		return reflect.TypeOf(""), nil
+	case *parse.BoolNode:
+		return reflect.TypeOf(false), nil
+	}
+	return nil, nil
+}
+
+// fields resolves a chain of field or method names starting at t.
+func (c *fieldChecker) fields(tree *parse.Tree, node parse.Node, t reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if t == nil {
+			return nil, nil
+		}
+		if m, ok := t.MethodByName(name); ok {
+			t = methodResult(m.Type)
+			continue
+		}
+		if t.Kind() == reflect.Pointer {
+			if m, ok := t.Elem().MethodByName(name); ok {
+				t = methodResult(m.Type)
+				continue
+			}
+			t = t.Elem()
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByName(name)
+			if !ok || !f.IsExported() {
+				location, _ := tree.ErrorContext(node)
+				return nil, fmt.Errorf("%s: can't evaluate field %s in type %s", location, name, t)
+			}
+			t = f.Type
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			return nil, nil
+		default:
+			location, _ := tree.ErrorContext(node)
+			return nil, fmt.Errorf("%s: can't evaluate field %s in type %s", location, name, t)
+		}
+	}
+	return t, nil
+}
+
+func methodResult(m reflect.Type) reflect.Type {
+	if m.NumOut() == 0 {
+		return nil
+	}
+	return m.Out(0)
+}
+
+// funcResult is the type returned by a template function called with
+// arguments of the given types, nil when unknown.
+func funcResult(name string, args []reflect.Type) reflect.Type {
+	if fn, ok := promptFuncs[name]; ok {
+		return methodResult(reflect.TypeOf(fn))
+	}
+	switch name {
+	case "printf", "print", "println", "html", "js", "urlquery":
+		return reflect.TypeOf("")
+	case "len":
+		return reflect.TypeOf(0)
+	case "not", "eq", "ne", "lt", "le", "gt", "ge":
+		return reflect.TypeOf(false)
+	case "slice":
+		if len(args) > 0 {
+			return args[0]
+		}
+	case "index":
+		if len(args) == 0 {
+			return nil
+		}
+		t := args[0]
+		for range args[1:] {
+			_, t = rangeTypes(t)
+		}
+		return t
+	}
+	return nil
+}
+
+// rangeTypes returns the key and element types of ranging over t.
+func rangeTypes(t reflect.Type) (reflect.Type, reflect.Type) {
+	if t == nil {
+		return nil, nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return reflect.TypeOf(0), t.Elem()
+	case reflect.Map:
+		return t.Key(), t.Elem()
+	case reflect.Int:
+		return t, t
+	}
+	return nil, nil
+}
+
+func copyVars(vars map[string]reflect.Type) map[string]reflect.Type {
+	out := make(map[string]reflect.Type, len(vars))
+	for k, v := range vars {
+		out[k] = v
+	}
+	return out
+}
@@ -26,10 +26,39 @@ var (
 		},
 		[]string{"method", "path"},
 	)
+
+	circuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"name"},
+	)
+
+	circuitBreakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_transitions_total",
+			Help: "Total number of circuit breaker state changes",
+		},
+		[]string{"name", "from", "to"},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration)
+	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration, circuitBreakerState, circuitBreakerTransitions)
+}
+
+// BreakerState sets the state of a circuit breaker without recording a
+// transition, e.g. when the breaker is created.
+func BreakerState(name string, state int) {
+	circuitBreakerState.WithLabelValues(name).Set(float64(state))
+}
+
+// BreakerStateChanged records a circuit breaker transition. State values
+// follow gobreaker: 0 closed, 1 half-open, 2 open.
+func BreakerStateChanged(name, from, to string, state int) {
+	circuitBreakerState.WithLabelValues(name).Set(float64(state))
+	circuitBreakerTransitions.WithLabelValues(name, from, to).Inc()
 }
 
 func GinMiddleware() gin.HandlerFunc {
@@ -0,0 +1,188 @@
+// Package retry implements the retry policy shared by outbound API clients:
+// exponential backoff with jitter, Retry-After support and context-aware
+// waits. Callers classify errors: only errors marked with Retryable or After
+// are retried, anything else is treated as permanent.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"inteam/internal/config"
+)
+
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Jitter is the share of the backoff delay that is randomized, 0..1.
+	Jitter float64
+}
+
+func FromConfig(cfg config.RetryConfig) Policy {
+	return Policy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   cfg.BaseDelay,
+		MaxDelay:    cfg.MaxDelay,
+		Jitter:      cfg.Jitter,
+	}
+}
+
+type retryableError struct {
+	err   error
+	after time.Duration
+	// hasAfter is set when the delay comes from the server (Retry-After) or
+	// the caller wants to retry immediately, bypassing backoff.
+	hasAfter bool
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Retryable marks err as transient; the next attempt waits for the backoff.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err}
+}
+
+// After marks err as transient and asks to retry after d. A zero d retries
+// immediately, e.g. with another credential.
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryableError{err: err, after: max(d, 0), hasAfter: true}
+}
+
+// Permanent strips the retry marker from err, e.g. once a streamed response
+// has been partly delivered and can no longer be repeated.
+func Permanent(err error) error {
+	return unwrap(err)
+}
+
+// IsRetryable reports whether err was marked with Retryable or After.
+func IsRetryable(err error) bool {
+	var re *retryableError
+	return errors.As(err, &re)
+}
+
+// Do calls fn until it succeeds, returns a permanent error, the attempts are
+// exhausted or ctx is done. An error asking to retry later than MaxDelay is
+// returned right away. The returned error is the last error without the
+// retry marker.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context, attempt int) error) error {
+	attempts := max(p.MaxAttempts, 1)
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err != nil {
+				return unwrap(err)
+			}
+			return ctxErr
+		}
+
+		err = fn(ctx, attempt)
+		if err == nil {
+			return nil
+		}
+
+		var re *retryableError
+		if !errors.As(err, &re) || attempt == attempts-1 {
+			return unwrap(err)
+		}
+
+		delay := p.Backoff(attempt)
+		if re.hasAfter {
+			// Retrying before the server allows it would only fail again.
+			if p.MaxDelay > 0 && re.after > p.MaxDelay {
+				return unwrap(err)
+			}
+			delay = re.after
+		}
+		if sleepErr := Sleep(ctx, delay); sleepErr != nil {
+			return unwrap(err)
+		}
+	}
+	return unwrap(err)
+}
+
+// Backoff returns the delay before attempt+1: BaseDelay doubled per attempt,
+// capped by MaxDelay, with the Jitter share of it randomized.
+func (p Policy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	delay -= delay * jitter * rand.Float64()
+	return time.Duration(delay)
+}
+
+func unwrap(err error) error {
+	var re *retryableError
+	if errors.As(err, &re) && err == error(re) {
+		return re.err
+	}
+	return err
+}
+
+// Sleep waits for d or until ctx is done.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// RetryAfter parses a Retry-After header given either in seconds or as an
+// HTTP date. It returns false when the header is missing or malformed.
+func RetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// StatusError classifies an HTTP error status: 429 and 5xx are retryable
+// (honouring Retry-After), other statuses are permanent.
+func StatusError(resp *http.Response, err error) error {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		if d, ok := RetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return After(err, d)
+		}
+		return Retryable(err)
+	case resp.StatusCode >= 500:
+		return Retryable(err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo_RetriesOnlyMarkedErrors(t *testing.T) {
+	p := Policy{MaxAttempts: 3}
+	permanent := errors.New("bad request")
+
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		if attempt < 2 {
+			return Retryable(errors.New("temporary"))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	calls = 0
+	err = p.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		return permanent
+	})
+	require.ErrorIs(t, err, permanent)
+	require.Equal(t, 1, calls)
+
+	calls = 0
+	err = p.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		return Retryable(permanent)
+	})
+	require.Same(t, permanent, err)
+	require.False(t, IsRetryable(err))
+	require.Equal(t, 3, calls)
+}
+
+func TestDo_StopsOnContextCancel(t *testing.T) {
+	p := Policy{MaxAttempts: 5, BaseDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := p.Do(ctx, func(ctx context.Context, attempt int) error {
+		calls++
+		return Retryable(errors.New("temporary"))
+	})
+	require.EqualError(t, err, "temporary")
+	require.Equal(t, 1, calls)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestDo_HonoursRetryAfter(t *testing.T) {
+	p := Policy{MaxAttempts: 2, BaseDelay: time.Hour}
+
+	start := time.Now()
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		if attempt == 0 {
+			return After(errors.New("switch token"), 0)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestDo_GivesUpWhenRetryAfterExceedsMaxDelay(t *testing.T) {
+	p := Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+	throttled := errors.New("too many requests")
+	calls := 0
+	err := p.Do(context.Background(), func(ctx context.Context, attempt int) error {
+		calls++
+		return After(throttled, time.Minute)
+	})
+	require.ErrorIs(t, err, throttled)
+	require.False(t, IsRetryable(err))
+	require.Equal(t, 1, calls)
+}
+
+func TestBackoff_GrowsAndCaps(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	require.Equal(t, 100*time.Millisecond, p.Backoff(0))
+	require.Equal(t, 200*time.Millisecond, p.Backoff(1))
+	require.Equal(t, 300*time.Millisecond, p.Backoff(5))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(1)
+		require.GreaterOrEqual(t, d, 100*time.Millisecond)
+		require.LessOrEqual(t, d, 200*time.Millisecond)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := RetryAfter("3", now)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, d)
+
+	d, ok = RetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	require.Equal(t, time.Minute, d)
+
+	_, ok = RetryAfter("soon", now)
+	require.False(t, ok)
+}
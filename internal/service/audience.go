@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"inteam/internal/domain"
+)
+
+const topCommentersLimit = 10
+
+// collectComments fetches comments for the most recent posts that have any.
+// Comments are best-effort: the first failure stops collection and is
+// reported as a source issue instead of aborting the analysis.
+func (s *profileService) collectComments(ctx context.Context, ownerID int64, wall []domain.WallPost) ([]domain.Comment, int, *domain.SourceIssue) {
+	if s.cfg.CommentPostsLimit <= 0 {
+		return nil, 0, nil
+	}
+
+	posts := make([]domain.WallPost, 0, len(wall))
+	for _, p := range wall {
+		if p.Comments > 0 {
+			posts = append(posts, p)
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].Date.After(posts[j].Date) })
+	if len(posts) > s.cfg.CommentPostsLimit {
+		posts = posts[:s.cfg.CommentPostsLimit]
+	}
+
+	var comments []domain.Comment
+	for i, p := range posts {
+		postOwner := p.OwnerID
+		if postOwner == 0 {
+			postOwner = ownerID
+		}
+
+		items, err := s.vkClient.GetComments(ctx, postOwner, p.ID, s.cfg.CommentsPerPost)
+		if err != nil {
+			issue := domain.SourceIssue{Source: domain.SourceComments, Code: "vk_error", Reason: err.Error()}
+			var derr *domain.Error
+			if errors.As(mapVKError(err), &derr) {
+				issue.Code, issue.Reason = derr.Code, derr.Message
+			}
+			return comments, i, &issue
+		}
+		comments = append(comments, items...)
+	}
+	return comments, len(posts), nil
+}
+
+func buildAudienceMetrics(ownerID int64, posts int, comments []domain.Comment, friends []domain.Friend) domain.AudienceMetrics {
+	metrics := domain.AudienceMetrics{
+		PostsAnalyzed:    posts,
+		CommentsAnalyzed: len(comments),
+	}
+
+	friendNames := make(map[int64]string, len(friends))
+	for _, f := range friends {
+		friendNames[f.ID] = f.FirstName + " " + f.LastName
+	}
+
+	var (
+		byAuthor     = make(map[int64]int)
+		othersIDs    = make(map[int64]struct{})
+		repliedTo    = make(map[int64]struct{})
+		othersCount  int
+		friendsCount int
+	)
+
+	for _, c := range comments {
+		if c.FromID == ownerID {
+			target := c.ReplyToComment
+			if target == 0 {
+				target = c.ParentID
+			}
+			if target != 0 {
+				repliedTo[target] = struct{}{}
+			}
+			continue
+		}
+
+		othersCount++
+		othersIDs[c.ID] = struct{}{}
+		byAuthor[c.FromID]++
+		if _, ok := friendNames[c.FromID]; ok {
+			friendsCount++
+		}
+	}
+
+	metrics.UniqueCommenters = len(byAuthor)
+	if othersCount == 0 {
+		return metrics
+	}
+
+	metrics.FriendCommentShare = float64(friendsCount) / float64(othersCount)
+
+	replied := 0
+	for id := range repliedTo {
+		if _, ok := othersIDs[id]; ok {
+			replied++
+		}
+	}
+	metrics.OwnerReplyRate = float64(replied) / float64(othersCount)
+
+	for id, n := range byAuthor {
+		name, isFriend := friendNames[id]
+		metrics.TopCommenters = append(metrics.TopCommenters, domain.CommenterStat{
+			ID:       id,
+			Name:     name,
+			Comments: n,
+			IsFriend: isFriend,
+		})
+	}
+	sort.Slice(metrics.TopCommenters, func(i, j int) bool {
+		a, b := metrics.TopCommenters[i], metrics.TopCommenters[j]
+		if a.Comments != b.Comments {
+			return a.Comments > b.Comments
+		}
+		return a.ID < b.ID
+	})
+	if len(metrics.TopCommenters) > topCommentersLimit {
+		metrics.TopCommenters = metrics.TopCommenters[:topCommentersLimit]
+	}
+
+	return metrics
+}
@@ -15,6 +15,7 @@ type AuthService interface {
 	Register(ctx context.Context, email, password string) (*domain.AuthUser, error)
 	Login(ctx context.Context, email, password string) (string, string, error)
 	GetUserByID(ctx context.Context, id uint) (*domain.AuthUser, error)
+	IsAdmin(ctx context.Context, id uint) (bool, error)
 }
 
 type authService struct {
@@ -86,3 +87,11 @@ func (s *authService) GetUserByID(ctx context.Context, id uint) (*domain.AuthUse
 	return s.users.GetByID(ctx, id)
 }
 
+func (s *authService) IsAdmin(ctx context.Context, id uint) (bool, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+	"inteam/internal/vk"
+)
+
+// CacheService lets operators force-refresh VK data for specific users or
+// communities (negative IDs).
+type CacheService interface {
+	Purge(ctx context.Context, vkIDs []int64) error
+	Warm(ctx context.Context, vkIDs []int64) (*WarmResult, error)
+}
+
+type WarmResult struct {
+	Warmed      int     `json:"warmed"`
+	Missing     []int64 `json:"missing"`
+	Deactivated []int64 `json:"deactivated"`
+}
+
+type cacheService struct {
+	vkClient vk.Client
+	logger   *zap.Logger
+}
+
+func NewCacheService(vkClient vk.Client, logger *zap.Logger) CacheService {
+	return &cacheService{
+		vkClient: vkClient,
+		logger:   logger,
+	}
+}
+
+func (s *cacheService) Purge(ctx context.Context, vkIDs []int64) error {
+	if err := s.vkClient.Invalidate(ctx, vkIDs...); err != nil {
+		return &domain.Error{Kind: domain.ErrUpstream, Code: "cache_broadcast_failed", Message: "cache purged locally, but other instances were not notified", Err: err}
+	}
+	s.logger.Info("vk cache purged", zap.Int64s("vk_ids", vkIDs))
+	return nil
+}
+
+// Warm drops stale entries and preloads users with a bulk users.get.
+// Communities are skipped: there is no bulk lookup for them.
+func (s *cacheService) Warm(ctx context.Context, vkIDs []int64) (*WarmResult, error) {
+	if err := s.Purge(ctx, vkIDs); err != nil {
+		return nil, err
+	}
+
+	var users []int64
+	for _, id := range vkIDs {
+		if id > 0 {
+			users = append(users, id)
+		}
+	}
+
+	res, err := s.vkClient.GetUsers(ctx, users, "")
+	if err != nil {
+		return nil, mapVKError(err)
+	}
+
+	return &WarmResult{
+		Warmed:      len(res.Users),
+		Missing:     res.Missing,
+		Deactivated: res.Deactivated,
+	}, nil
+}
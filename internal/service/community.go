@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+	"inteam/internal/llm"
+	"inteam/internal/vk"
+)
+
+// CommunityOwnerID converts a community ID into the negative owner ID used
+// for its wall and for storing its analysis.
+func CommunityOwnerID(groupID int64) int64 {
+	if groupID < 0 {
+		return groupID
+	}
+	return -groupID
+}
+
+func (s *profileService) AnalyzeCommunity(ctx context.Context, groupID int64) (*domain.Profile, error) {
+	tracer := otel.Tracer("inteam/service/profile")
+	ctx, span := tracer.Start(ctx, "AnalyzeCommunity")
+	ownerID := CommunityOwnerID(groupID)
+	span.SetAttributes(attribute.Int64("vk.owner_id", ownerID))
+	defer span.End()
+
+	if err := s.summarizer.CheckPrompt(ctx, llm.PromptCommunity); err != nil {
+		return nil, mapSummaryError(err)
+	}
+
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	var data domain.CommunityData
+	err := runStage(ctx, stageFetch, s.cfg.FetchTimeout, func(ctx context.Context) error {
+		var err error
+		data, err = s.collectCommunityData(ctx, ownerID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("profile.partial", data.Partial))
+
+	var summary llm.Summary
+	err = runStage(ctx, stageSummarize, s.cfg.SummarizeTimeout, func(ctx context.Context) error {
+		var err error
+		summary, err = s.summarizer.GenerateCommunitySummary(ctx, data)
+		if err != nil {
+			return mapSummaryError(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &domain.Profile{
+		VKID:          ownerID,
+		ScreenName:    data.Community.ScreenName,
+		FullName:      data.Community.Name,
+		RawJSON:       string(raw),
+		Summary:       summary.Text,
+		PromptVersion: summary.PromptVersion,
+		Partial:       data.Partial,
+		UpdatedAt:     time.Now(),
+	}
+
+	err = runStage(ctx, stagePersist, s.cfg.PersistTimeout, func(ctx context.Context) error {
+		if err := s.profileRepo.Save(ctx, profile); err != nil {
+			return err
+		}
+
+		if s.storage != nil {
+			if err := s.storage.SaveProfileSnapshot(ctx, ownerID, raw); err != nil {
+				s.logger.Warn("failed to save community snapshot to object storage", zap.Error(err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (s *profileService) collectCommunityData(ctx context.Context, ownerID int64) (domain.CommunityData, error) {
+	community, err := s.vkClient.GetCommunity(ctx, -ownerID)
+	if err != nil {
+		return domain.CommunityData{}, mapCommunityError(err)
+	}
+
+	data := domain.CommunityData{Community: *community}
+
+	wall, total, err := s.vkClient.GetAllWall(ctx, ownerID, s.cfg.WallLimit)
+	if err != nil {
+		mapped := mapVKError(err)
+		var derr *domain.Error
+		if !errors.As(mapped, &derr) || !errors.Is(derr, domain.ErrForbidden) {
+			return domain.CommunityData{}, mapped
+		}
+		data.Unavailable = append(data.Unavailable, domain.SourceIssue{
+			Source: domain.SourceWall,
+			Code:   derr.Code,
+			Reason: derr.Message,
+		})
+		data.Partial = true
+	}
+	data.Wall = wall
+	data.WallTotal = total
+	data.Vector = buildCommunityVector(data)
+	return data, nil
+}
+
+func mapCommunityError(err error) error {
+	if errors.Is(err, vk.ErrNotFound) {
+		return &domain.Error{Kind: domain.ErrNotFound, Code: "community_not_found", Message: "vk community not found", Err: err}
+	}
+	return mapVKError(err)
+}
+
+func buildCommunityVector(data domain.CommunityData) domain.CommunityVector {
+	wall := data.Wall
+	v := domain.CommunityVector{MembersCount: data.Community.MembersCount}
+	if len(wall) == 0 {
+		return v
+	}
+
+	var (
+		minDate     = wall[0].Date
+		maxDate     = wall[0].Date
+		totalLen    int
+		totalEng    int
+		totalViews  int
+		reposts     int
+		attachments int
+	)
+
+	for _, p := range wall {
+		if p.Date.Before(minDate) {
+			minDate = p.Date
+		}
+		if p.Date.After(maxDate) {
+			maxDate = p.Date
+		}
+		totalLen += len(p.Text)
+		totalEng += p.Likes + p.Comments + p.Reposts
+		totalViews += p.Views
+		attachments += len(p.Attachments)
+		if p.IsRepost() {
+			reposts++
+		}
+	}
+
+	months := maxDate.Sub(minDate).Hours() / (24 * 30)
+	if months < 1 {
+		months = 1
+	}
+
+	n := float64(len(wall))
+	v.PostsPerMonth = n / months
+	v.AveragePostLen = float64(totalLen) / n
+	v.EngagementRate = float64(totalEng) / n
+	v.ViewsPerPost = float64(totalViews) / n
+	v.RepostRatio = float64(reposts) / n
+	v.AttachmentsPerPost = float64(attachments) / n
+	v.LastPostAt = maxDate
+
+	if members := data.Community.MembersCount; members > 0 {
+		v.EngagementPerMember = v.EngagementRate / float64(members)
+		v.ReachRate = v.ViewsPerPost / float64(members)
+	}
+	return v
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sony/gobreaker"
+
+	"inteam/internal/domain"
+	"inteam/internal/llm"
+	"inteam/internal/vk"
+)
+
+func mapVKError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	var derr *domain.Error
+	if errors.As(err, &derr) {
+		return err
+	}
+
+	wrap := func(kind error, code, message string) error {
+		return &domain.Error{Kind: kind, Code: code, Message: message, Err: err}
+	}
+
+	switch {
+	case errors.Is(err, vk.ErrUserDeleted):
+		return wrap(domain.ErrNotFound, "user_deleted", "vk user is deleted or banned")
+	case errors.Is(err, vk.ErrNotFound):
+		return wrap(domain.ErrNotFound, "user_not_found", "vk user not found")
+	case errors.Is(err, vk.ErrProfilePrivate):
+		return wrap(domain.ErrForbidden, "profile_private", "vk profile is private")
+	case errors.Is(err, vk.ErrAccessDenied):
+		return wrap(domain.ErrForbidden, "access_denied", "access to vk profile data denied")
+	case errors.Is(err, vk.ErrTooManyRequests):
+		return wrap(domain.ErrRateLimited, "too_many_requests", "vk rate limit exceeded, retry later")
+	case errors.Is(err, vk.ErrInvalidToken), errors.Is(err, vk.ErrNoAvailableTokens):
+		return wrap(domain.ErrUpstream, "vk_auth_failed", "vk api credentials rejected")
+	case errors.Is(err, gobreaker.ErrOpenState), errors.Is(err, gobreaker.ErrTooManyRequests):
+		return wrap(domain.ErrUpstream, "vk_unavailable", "vk api temporarily unavailable")
+	default:
+		return wrap(domain.ErrUpstream, "vk_error", "vk api request failed")
+	}
+}
+
+func mapSummaryError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, llm.ErrUnknownPrompt) {
+		return &domain.Error{Kind: domain.ErrInvalidInput, Code: "unknown_prompt", Message: "unknown prompt template", Err: err}
+	}
+	return &domain.Error{Kind: domain.ErrUpstream, Code: "summary_failed", Message: "failed to generate profile summary", Err: err}
+}
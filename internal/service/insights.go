@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+)
+
+type insightsResult struct {
+	insights *domain.Insights
+	err      error
+}
+
+// extractInsights runs the insights request alongside the summary.
+func (s *profileService) extractInsights(ctx context.Context, data domain.ProfileData) <-chan insightsResult {
+	done := make(chan insightsResult, 1)
+	go func() {
+		if s.cfg.InsightsTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.cfg.InsightsTimeout)
+			defer cancel()
+		}
+		insights, err := s.summarizer.ExtractProfileInsights(ctx, data)
+		done <- insightsResult{insights: insights, err: err}
+	}()
+	return done
+}
+
+// awaitInsights waits for extractInsights; failures only drop the insights.
+func (s *profileService) awaitInsights(done <-chan insightsResult) *domain.Insights {
+	res := <-done
+	if res.err != nil {
+		s.logger.Warn("failed to extract profile insights, saving summary only", zap.Error(res.err))
+		return nil
+	}
+	return res.insights
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"inteam/internal/domain"
+)
+
+const otherInterestCategory = "Другое"
+
+// interestCategories maps the VK community "activity" field (e.g. "Музыка",
+// "Компьютерные игры") to a coarse interest category. Stems match the start
+// of a word and are long enough not to catch unrelated words ("мод" would
+// match "модели"); short keywords are listed as whole words. The first
+// matching category wins.
+var interestCategories = []struct {
+	category string
+	stems    []string
+	words    []string
+}{
+	{"Музыка", []string{"музык", "исполнител", "музыкант", "радио", "концерт"}, nil},
+	{"Кино и сериалы", []string{"фильм", "кинематограф", "кинотеатр", "сериал", "аниме", "мультфильм", "телевиден"}, []string{"кино"}},
+	{"Игры", []string{"игров", "киберспорт", "геймер"}, []string{"игры", "игра", "игр"}},
+	{"IT и технологии", []string{"программ", "технолог", "интернет", "компьютер", "гаджет", "электроник"}, []string{"it", "айти", "софт"}},
+	{"Наука и образование", []string{"наук", "образован", "учеб", "университет", "школ", "студен", "научн"}, []string{"вуз", "вузы"}},
+	{"Спорт и фитнес", []string{"спорт", "фитнес", "футбол", "хоккей", "баскетбол", "единоборств"}, []string{"бег", "йога"}},
+	{"Юмор", []string{"юмор", "развлечен"}, []string{"мем", "мемы"}},
+	{"Новости и общество", []string{"новост", "политик", "общество", "журнал", "город", "блог"}, []string{"сми"}},
+	{"Путешествия", []string{"путешеств", "туризм", "туристич", "отдых"}, nil},
+	{"Еда и кулинария", []string{"кулинар", "рецепт", "ресторан"}, []string{"еда", "кафе"}},
+	{"Красота и мода", []string{"красот", "модн", "одежд", "стиль", "косметик", "обув"}, []string{"мода"}},
+	{"Бизнес и финансы", []string{"бизнес", "финанс", "инвест", "предпринима", "маркетинг", "работ", "вакан", "банк", "экономик"}, nil},
+	{"Авто и мото", []string{"автомоб", "мотоцикл", "машин"}, []string{"авто", "мото"}},
+	{"Искусство и культура", []string{"искусств", "культур", "театр", "музе", "фотограф", "дизайн", "живопис", "творчеств"}, nil},
+	{"Книги и литература", []string{"книг", "литератур", "поэз", "писател"}, nil},
+	{"Семья и дети", []string{"семья", "детск", "родител", "материнств"}, []string{"дети", "детей"}},
+	{"Здоровье", []string{"здоров", "медицин", "психолог"}, nil},
+	{"Животные", []string{"животн", "питомц", "собак", "кошк"}, nil},
+}
+
+func interestCategory(activity string) string {
+	activity = strings.ToLower(strings.TrimSpace(activity))
+	if activity == "" {
+		return otherInterestCategory
+	}
+
+	words := strings.FieldsFunc(activity, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '/' || r == '-' || r == '(' || r == ')'
+	})
+	for _, entry := range interestCategories {
+		for _, w := range words {
+			if slices.Contains(entry.words, w) {
+				return entry.category
+			}
+			for _, stem := range entry.stems {
+				if strings.HasPrefix(w, stem) {
+					return entry.category
+				}
+			}
+		}
+	}
+	return otherInterestCategory
+}
+
+// interestDistribution counts communities per interest category, deduplicating
+// communities that appear both among groups and subscriptions.
+func interestDistribution(sets ...[]domain.Community) []domain.InterestShare {
+	seen := make(map[int64]struct{})
+	counts := make(map[string]int)
+	total := 0
+
+	for _, set := range sets {
+		for _, g := range set {
+			if _, ok := seen[g.ID]; ok {
+				continue
+			}
+			seen[g.ID] = struct{}{}
+			counts[interestCategory(g.Activity)]++
+			total++
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	shares := make([]domain.InterestShare, 0, len(counts))
+	for category, n := range counts {
+		shares = append(shares, domain.InterestShare{
+			Category: category,
+			Count:    n,
+			Share:    float64(n) / float64(total),
+		})
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Count != shares[j].Count {
+			return shares[i].Count > shares[j].Count
+		}
+		return shares[i].Category < shares[j].Category
+	})
+	return shares
+}
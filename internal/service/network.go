@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+)
+
+// collectNetwork builds the ego network of the analyzed user from mutual
+// friends and samples friend lists to estimate the two-hop reach. Like
+// comments, the network is optional and failures only mark it unavailable.
+func (s *profileService) collectNetwork(ctx context.Context, ownerID int64, friends []domain.Friend) (*domain.EgoNetwork, domain.NetworkMetrics, *domain.SourceIssue) {
+	var ids []int64
+	for _, f := range friends {
+		if !f.IsDeactivated() {
+			ids = append(ids, f.ID)
+		}
+	}
+	if s.cfg.NetworkFriendsLimit > 0 && len(ids) > s.cfg.NetworkFriendsLimit {
+		ids = ids[:s.cfg.NetworkFriendsLimit]
+	}
+
+	mutual, err := s.vkClient.GetMutualFriends(ctx, ownerID, ids)
+	if err != nil {
+		return nil, networkMetrics(friends, nil, nil, 0), networkIssue(err)
+	}
+	network := buildEgoNetwork(ownerID, ids, mutual)
+
+	var lists map[int64][]int64
+	if s.cfg.NetworkReachLimit > 0 {
+		sample := ids
+		if len(sample) > s.cfg.NetworkReachLimit {
+			sample = sample[:s.cfg.NetworkReachLimit]
+		}
+		lists, err = s.vkClient.GetFriendIDs(ctx, sample, 0)
+		if err != nil {
+			return network, networkMetrics(friends, network, nil, ownerID), networkIssue(err)
+		}
+	}
+
+	return network, networkMetrics(friends, network, lists, ownerID), nil
+}
+
+func networkIssue(err error) *domain.SourceIssue {
+	issue := domain.SourceIssue{Source: domain.SourceNetwork, Code: "vk_error", Reason: err.Error()}
+	var derr *domain.Error
+	if errors.As(mapVKError(err), &derr) {
+		issue.Code, issue.Reason = derr.Code, derr.Message
+	}
+	return &issue
+}
+
+func (s *profileService) saveEgoNetwork(ctx context.Context, network *domain.EgoNetwork) {
+	if s.storage == nil || network == nil {
+		return
+	}
+	raw, err := json.Marshal(network)
+	if err == nil {
+		err = s.storage.SaveEgoNetwork(ctx, network.OwnerID, raw)
+	}
+	if err != nil {
+		s.logger.Warn("failed to save ego network to object storage", zap.Error(err))
+	}
+}
+
+// buildEgoNetwork keeps only edges between the given nodes and stores every
+// undirected edge once with the smaller ID first.
+func buildEgoNetwork(ownerID int64, nodes []int64, mutual map[int64][]int64) *domain.EgoNetwork {
+	inNetwork := make(map[int64]struct{}, len(nodes))
+	for _, id := range nodes {
+		inNetwork[id] = struct{}{}
+	}
+
+	seen := make(map[[2]int64]struct{})
+	network := &domain.EgoNetwork{OwnerID: ownerID, Nodes: nodes}
+	for _, a := range nodes {
+		for _, b := range mutual[a] {
+			if _, ok := inNetwork[b]; !ok || a == b {
+				continue
+			}
+			edge := [2]int64{min(a, b), max(a, b)}
+			if _, ok := seen[edge]; ok {
+				continue
+			}
+			seen[edge] = struct{}{}
+			network.Edges = append(network.Edges, edge)
+		}
+	}
+
+	sort.Slice(network.Edges, func(i, j int) bool {
+		if network.Edges[i][0] != network.Edges[j][0] {
+			return network.Edges[i][0] < network.Edges[j][0]
+		}
+		return network.Edges[i][1] < network.Edges[j][1]
+	})
+	return network
+}
+
+// networkMetrics computes the ego network statistics. The clustering
+// coefficient is the average local coefficient over all friends (nodes with
+// fewer than two neighbours count as zero). Reach is the number of distinct
+// users two hops away among the sampled friend lists.
+func networkMetrics(friends []domain.Friend, network *domain.EgoNetwork, lists map[int64][]int64, ownerID int64) domain.NetworkMetrics {
+	var metrics domain.NetworkMetrics
+
+	if len(friends) > 0 {
+		deactivated := 0
+		for _, f := range friends {
+			if f.IsDeactivated() {
+				deactivated++
+			}
+		}
+		metrics.DeactivatedShare = float64(deactivated) / float64(len(friends))
+	}
+
+	if network == nil || len(network.Nodes) == 0 {
+		return metrics
+	}
+
+	n := len(network.Nodes)
+	metrics.FriendsAnalyzed = n
+	metrics.Edges = len(network.Edges)
+	if n > 1 {
+		metrics.Density = float64(len(network.Edges)) / (float64(n) * float64(n-1) / 2)
+	}
+
+	adj := make(map[int64]map[int64]struct{}, n)
+	for _, e := range network.Edges {
+		for _, pair := range [][2]int64{{e[0], e[1]}, {e[1], e[0]}} {
+			if adj[pair[0]] == nil {
+				adj[pair[0]] = make(map[int64]struct{})
+			}
+			adj[pair[0]][pair[1]] = struct{}{}
+		}
+	}
+
+	var total float64
+	for _, node := range network.Nodes {
+		neighbours := adj[node]
+		k := len(neighbours)
+		if k < 2 {
+			continue
+		}
+		links := 0
+		for a := range neighbours {
+			for b := range neighbours {
+				if a < b {
+					if _, ok := adj[a][b]; ok {
+						links++
+					}
+				}
+			}
+		}
+		total += float64(links) / (float64(k) * float64(k-1) / 2)
+	}
+	metrics.ClusteringCoefficient = total / float64(n)
+
+	if len(lists) > 0 {
+		direct := make(map[int64]struct{}, len(friends))
+		for _, f := range friends {
+			direct[f.ID] = struct{}{}
+		}
+		reach := make(map[int64]struct{})
+		for _, list := range lists {
+			for _, id := range list {
+				if _, ok := direct[id]; ok || id == ownerID {
+					continue
+				}
+				reach[id] = struct{}{}
+			}
+		}
+		metrics.FriendOfFriendReach = len(reach)
+		metrics.ReachSampled = len(lists)
+	}
+
+	return metrics
+}
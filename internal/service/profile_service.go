@@ -3,14 +3,16 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 
+	"inteam/internal/config"
 	"inteam/internal/domain"
-	"inteam/internal/gigachat"
+	"inteam/internal/llm"
 	"inteam/internal/repository"
 	"inteam/internal/storage"
 	"inteam/internal/vk"
@@ -19,26 +21,37 @@ import (
 type ProfileService interface {
 	GetProfile(ctx context.Context, vkID int64) (*domain.Profile, error)
 	AnalyzeProfile(ctx context.Context, vkID int64) (*domain.Profile, error)
+	// AnalyzeProfileStream is AnalyzeProfile reporting stages, the collected
+	// data and summary chunks through emit as the analysis goes.
+	AnalyzeProfileStream(ctx context.Context, vkID int64, emit func(AnalysisEvent) error) (*domain.Profile, error)
+	// CheckProfilePrompt fails with an invalid input error when the prompt
+	// template selected for ctx is unknown.
+	CheckProfilePrompt(ctx context.Context) error
+	AnalyzeCommunity(ctx context.Context, groupID int64) (*domain.Profile, error)
+	ResolveVKID(ctx context.Context, ref string) (int64, error)
 }
 
 type profileService struct {
+	cfg         config.AnalysisConfig
 	vkClient    vk.Client
-	gigachat    gigachat.Client
+	summarizer  llm.Summarizer
 	profileRepo repository.ProfileRepository
 	storage     storage.ObjectStorage
 	logger      *zap.Logger
 }
 
 func NewProfileService(
+	cfg config.AnalysisConfig,
 	vkClient vk.Client,
-	gigachat gigachat.Client,
+	summarizer llm.Summarizer,
 	profileRepo repository.ProfileRepository,
 	storage storage.ObjectStorage,
 	logger *zap.Logger,
 ) ProfileService {
 	return &profileService{
+		cfg:         cfg,
 		vkClient:    vkClient,
-		gigachat:    gigachat,
+		summarizer:  summarizer,
 		profileRepo: profileRepo,
 		storage:     storage,
 		logger:      logger,
@@ -49,93 +62,296 @@ func (s *profileService) GetProfile(ctx context.Context, vkID int64) (*domain.Pr
 	return s.profileRepo.GetByVKID(ctx, vkID)
 }
 
+func (s *profileService) ResolveVKID(ctx context.Context, ref string) (int64, error) {
+	parsed, err := vk.ParseProfileRef(ref)
+	if err != nil {
+		return 0, &domain.Error{Kind: domain.ErrInvalidInput, Code: "invalid_profile_ref", Message: "expected a numeric vk id, screen name or vk.com profile url", Err: err}
+	}
+	if parsed.ID != 0 {
+		return parsed.ID, nil
+	}
+
+	vkID, err := s.vkClient.ResolveScreenName(ctx, parsed.ScreenName)
+	if err != nil {
+		return 0, mapVKError(err)
+	}
+	return vkID, nil
+}
+
 func (s *profileService) AnalyzeProfile(ctx context.Context, vkID int64) (*domain.Profile, error) {
+	return s.analyzeProfile(ctx, vkID, nil)
+}
+
+func (s *profileService) AnalyzeProfileStream(ctx context.Context, vkID int64, emit func(AnalysisEvent) error) (*domain.Profile, error) {
+	return s.analyzeProfile(ctx, vkID, emit)
+}
+
+func (s *profileService) CheckProfilePrompt(ctx context.Context) error {
+	if err := s.summarizer.CheckPrompt(ctx, llm.PromptProfile); err != nil {
+		return mapSummaryError(err)
+	}
+	return nil
+}
+
+// analyzeProfile streams the summary when emit is set and generates it in
+// one piece otherwise.
+func (s *profileService) analyzeProfile(ctx context.Context, vkID int64, emit func(AnalysisEvent) error) (*domain.Profile, error) {
 	tracer := otel.Tracer("inteam/service/profile")
 	ctx, span := tracer.Start(ctx, "AnalyzeProfile")
-	span.SetAttributes(attribute.Int64("vk.id", vkID))
+	span.SetAttributes(
+		attribute.Int64("vk.id", vkID),
+		attribute.Bool("stream", emit != nil),
+	)
 	defer span.End()
 
-	user, err := s.vkClient.GetUser(ctx, vkID)
-	if err != nil {
+	if err := s.CheckProfilePrompt(ctx); err != nil {
 		return nil, err
 	}
 
-	wall, err := s.vkClient.GetWall(ctx, vkID, 0, 100)
-	if err != nil {
-		return nil, err
+	ctx, cancel := s.withBudget(ctx)
+	defer cancel()
+
+	notify := func(ev AnalysisEvent) error {
+		if emit == nil {
+			return nil
+		}
+		return emit(ev)
 	}
 
-	gifts, err := s.vkClient.GetGifts(ctx, vkID, 0, 100)
-	if err != nil {
+	if err := notify(AnalysisEvent{Type: EventStage, Stage: stageFetch}); err != nil {
 		return nil, err
 	}
-
-	friends, err := s.vkClient.GetFriends(ctx, vkID, 0, 100)
+	var (
+		data    domain.ProfileData
+		network *domain.EgoNetwork
+	)
+	err := runStage(ctx, stageFetch, s.cfg.FetchTimeout, func(ctx context.Context) error {
+		var err error
+		data, network, err = s.collectProfileData(ctx, vkID)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(attribute.Bool("profile.partial", data.Partial))
 
-	vector := buildActivityVector(wall, gifts, friends)
-
-	data := domain.ProfileData{
-		User:    *user,
-		Wall:    wall,
-		Gifts:   gifts,
-		Friends: friends,
-		Vector:  vector,
+	if err := notify(fetchedEvent(data)); err != nil {
+		return nil, err
 	}
+	if err := notify(AnalysisEvent{Type: EventStage, Stage: stageSummarize}); err != nil {
+		return nil, err
+	}
+	insightsCtx, cancelInsights := context.WithCancel(ctx)
+	defer cancelInsights()
+	extracted := s.extractInsights(insightsCtx, data)
 
-	summary, err := s.gigachat.GenerateProfileSummary(ctx, data)
+	var summary llm.Summary
+	err = runStage(ctx, stageSummarize, s.cfg.SummarizeTimeout, func(ctx context.Context) error {
+		var err error
+		if emit == nil {
+			summary, err = s.summarizer.GenerateProfileSummary(ctx, data)
+		} else {
+			summary, err = s.summarizer.StreamProfileSummary(ctx, data, func(delta string) error {
+				return emit(AnalysisEvent{Type: EventSummary, Delta: delta})
+			})
+		}
+		if err != nil {
+			return mapSummaryError(err)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	insights := s.awaitInsights(extracted)
+	if insights != nil {
+		if err := notify(AnalysisEvent{Type: EventInsights, Insights: insights}); err != nil {
+			return nil, err
+		}
+	}
 
 	raw, err := json.Marshal(data)
 	if err != nil {
 		return nil, err
 	}
 
+	user := data.User
 	fullName := user.FirstName + " " + user.LastName
 
 	profile := &domain.Profile{
-		VKID:       vkID,
-		ScreenName: user.ScreenName,
-		FullName:   fullName,
-		RawJSON:    string(raw),
-		Summary:    summary,
-		UpdatedAt:  time.Now(),
+		VKID:          vkID,
+		ScreenName:    user.ScreenName,
+		FullName:      fullName,
+		RawJSON:       string(raw),
+		Summary:       summary.Text,
+		PromptVersion: summary.PromptVersion,
+		Insights:      insights,
+		Partial:       data.Partial,
+		UpdatedAt:     time.Now(),
 	}
 
-	if err := s.profileRepo.Save(ctx, profile); err != nil {
+	if err := notify(AnalysisEvent{Type: EventStage, Stage: stagePersist}); err != nil {
 		return nil, err
 	}
+	err = runStage(ctx, stagePersist, s.cfg.PersistTimeout, func(ctx context.Context) error {
+		if err := s.profileRepo.Save(ctx, profile); err != nil {
+			return err
+		}
 
-	if s.storage != nil {
-		if err := s.storage.SaveProfileSnapshot(ctx, vkID, raw); err != nil {
-			s.logger.Warn("failed to save profile snapshot to object storage", zap.Error(err))
+		if s.storage != nil {
+			if err := s.storage.SaveProfileSnapshot(ctx, vkID, raw); err != nil {
+				s.logger.Warn("failed to save profile snapshot to object storage", zap.Error(err))
+			}
 		}
+		s.saveEgoNetwork(ctx, network)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return profile, nil
 }
 
-func buildActivityVector(wall []domain.WallPost, gifts []domain.Gift, friends []domain.Friend) domain.ActivityVector {
+// collectProfileData fetches everything the analysis needs from VK and
+// builds the activity vector.
+func (s *profileService) collectProfileData(ctx context.Context, vkID int64) (domain.ProfileData, *domain.EgoNetwork, error) {
+	bundle, err := s.vkClient.GetProfileBundle(ctx, vkID, vk.BundleRequest{
+		WallLimit:          s.cfg.WallLimit,
+		GiftsLimit:         s.cfg.GiftsLimit,
+		FriendsLimit:       s.cfg.FriendsLimit,
+		GroupsLimit:        s.cfg.GroupsLimit,
+		SubscriptionsLimit: s.cfg.SubscriptionsLimit,
+		PhotosLimit:        s.cfg.PhotosLimit,
+		AlbumsLimit:        s.cfg.AlbumsLimit,
+	})
+	if err != nil {
+		return domain.ProfileData{}, nil, mapVKError(err)
+	}
+	if err := bundle.Errors[domain.SourceUser]; err != nil {
+		return domain.ProfileData{}, nil, mapVKError(err)
+	}
+	if bundle.User == nil {
+		return domain.ProfileData{}, nil, mapVKError(vk.ErrNotFound)
+	}
+
+	unavailable, err := sourceIssues(bundle,
+		domain.SourceWall,
+		domain.SourceGifts,
+		domain.SourceFriends,
+		domain.SourceGroups,
+		domain.SourceSubscriptions,
+		domain.SourcePhotos,
+		domain.SourceAlbums,
+	)
+	if err != nil {
+		return domain.ProfileData{}, nil, err
+	}
+
+	user := bundle.User
+	data := domain.ProfileData{
+		User:          *user,
+		Wall:          bundle.Wall,
+		Gifts:         bundle.Gifts,
+		Friends:       bundle.Friends,
+		Groups:        bundle.Groups,
+		Subscriptions: bundle.Subscriptions,
+		Photos:        bundle.Photos,
+		Albums:        bundle.Albums,
+		WallTotal:     bundle.WallTotal,
+		GiftsTotal:    bundle.GiftsTotal,
+		FriendsTotal:  bundle.FriendsTotal,
+		GroupsTotal:   bundle.GroupsTotal,
+		PhotosTotal:   bundle.PhotosTotal,
+		AlbumsTotal:   bundle.AlbumsTotal,
+		Interests:     interestDistribution(bundle.Groups, bundle.Subscriptions),
+		Unavailable:   unavailable,
+		Partial:       len(unavailable) > 0,
+	}
+
+	if data.IsAvailable(domain.SourceWall) {
+		comments, posts, issue := s.collectComments(ctx, vkID, data.Wall)
+		if issue != nil {
+			data.Unavailable = append(data.Unavailable, *issue)
+			data.Partial = true
+		}
+		data.Comments = comments
+		data.Audience = buildAudienceMetrics(vkID, posts, comments, data.Friends)
+	}
+
+	var network *domain.EgoNetwork
+	if data.IsAvailable(domain.SourceFriends) && s.cfg.NetworkFriendsLimit > 0 && len(data.Friends) > 0 {
+		var issue *domain.SourceIssue
+		network, data.Network, issue = s.collectNetwork(ctx, vkID, data.Friends)
+		if issue != nil {
+			data.Unavailable = append(data.Unavailable, *issue)
+			data.Partial = true
+		}
+	}
+
+	data.Vector = buildActivityVector(data)
+	return data, network, nil
+}
+
+// sourceIssues turns per-source fetch errors into SourceIssue records. Only
+// restrictions on the profile (closed or missing sections) are tolerated; any
+// other failure aborts the analysis.
+func sourceIssues(bundle *vk.ProfileBundle, sources ...domain.Source) ([]domain.SourceIssue, error) {
+	var issues []domain.SourceIssue
+	for _, source := range sources {
+		err := bundle.Errors[source]
+		if err == nil {
+			continue
+		}
+
+		mapped := mapVKError(err)
+		var derr *domain.Error
+		if !errors.As(mapped, &derr) || !(errors.Is(derr, domain.ErrForbidden) || errors.Is(derr, domain.ErrNotFound)) {
+			return nil, mapped
+		}
+
+		issues = append(issues, domain.SourceIssue{
+			Source: source,
+			Code:   derr.Code,
+			Reason: derr.Message,
+		})
+	}
+	return issues, nil
+}
+
+func buildActivityVector(data domain.ProfileData) domain.ActivityVector {
+	wall := data.Wall
+	giftsCount := max(data.GiftsTotal, len(data.Gifts))
+	friendsCount := max(data.FriendsTotal, len(data.Friends))
+	groupsCount := max(data.GroupsTotal, len(data.Groups))
+	completeness := profileCompleteness(data.User)
+
+	var missing []domain.Source
+	for _, issue := range data.Unavailable {
+		missing = append(missing, issue.Source)
+	}
+
 	if len(wall) == 0 {
-		return domain.ActivityVector{
+		return withPhotoActivity(domain.ActivityVector{
 			PostsPerMonth:       0,
 			AveragePostLen:      0,
 			EngagementRate:      0,
-			GiftsCount:          len(gifts),
-			FriendsCount:        len(friends),
-			ProfileCompleteness: 0,
-		}
+			GiftsCount:          giftsCount,
+			FriendsCount:        friendsCount,
+			ProfileCompleteness: completeness,
+			FollowersCount:      data.User.FollowersCount,
+			GroupsCount:         groupsCount,
+			MissingSources:      missing,
+		}, data)
 	}
 
 	var (
-		minDate  = wall[0].Date
-		maxDate  = wall[0].Date
-		totalLen int
-		totalEng int
+		minDate     = wall[0].Date
+		maxDate     = wall[0].Date
+		totalLen    int
+		totalEng    int
+		originals   int
+		attachments int
 	)
 
 	for _, p := range wall {
@@ -145,8 +361,15 @@ func buildActivityVector(wall []domain.WallPost, gifts []domain.Gift, friends []
 		if p.Date.After(maxDate) {
 			maxDate = p.Date
 		}
-		totalLen += len(p.Text)
 		totalEng += p.Likes + p.Comments + p.Reposts
+		attachments += len(p.Attachments)
+
+		// Reposts usually carry little or no own text, so only original
+		// posts count towards the average length.
+		if !p.IsRepost() {
+			originals++
+			totalLen += len(p.Text)
+		}
 	}
 
 	months := maxDate.Sub(minDate).Hours() / (24 * 30)
@@ -155,15 +378,96 @@ func buildActivityVector(wall []domain.WallPost, gifts []domain.Gift, friends []
 	}
 
 	postsPerMonth := float64(len(wall)) / months
-	avgLen := float64(totalLen) / float64(len(wall))
 	engagement := float64(totalEng) / float64(len(wall))
+	reposts := len(wall) - originals
 
-	return domain.ActivityVector{
+	var avgLen float64
+	if originals > 0 {
+		avgLen = float64(totalLen) / float64(originals)
+	}
+
+	return withPhotoActivity(domain.ActivityVector{
 		PostsPerMonth:       postsPerMonth,
 		AveragePostLen:      avgLen,
 		EngagementRate:      engagement,
-		GiftsCount:          len(gifts),
-		FriendsCount:        len(friends),
-		ProfileCompleteness: 0,
+		OriginalPostsCount:  originals,
+		RepostsCount:        reposts,
+		RepostRatio:         float64(reposts) / float64(len(wall)),
+		OriginalRatio:       float64(originals) / float64(len(wall)),
+		AttachmentsPerPost:  float64(attachments) / float64(len(wall)),
+		GiftsCount:          giftsCount,
+		FriendsCount:        friendsCount,
+		ProfileCompleteness: completeness,
+		FollowersCount:      data.User.FollowersCount,
+		GroupsCount:         groupsCount,
+		MissingSources:      missing,
+	}, data)
+}
+
+// withPhotoActivity adds photo cadence to the vector. Photos are a separate
+// signal from the wall: many profiles post pictures without any text.
+func withPhotoActivity(v domain.ActivityVector, data domain.ProfileData) domain.ActivityVector {
+	photos := data.Photos
+	v.PhotosCount = max(data.PhotosTotal, len(photos))
+	v.AlbumsCount = max(data.AlbumsTotal, len(data.Albums))
+	if len(photos) == 0 {
+		return v
+	}
+
+	var (
+		minDate = photos[0].Date
+		maxDate = photos[0].Date
+		likes   int
+		placed  int
+	)
+	for _, p := range photos {
+		if p.Date.Before(minDate) {
+			minDate = p.Date
+		}
+		if p.Date.After(maxDate) {
+			maxDate = p.Date
+		}
+		likes += p.Likes
+		if p.HasPlace() {
+			placed++
+		}
+	}
+
+	months := maxDate.Sub(minDate).Hours() / (24 * 30)
+	if months < 1 {
+		months = 1
+	}
+
+	v.PhotosPerMonth = float64(len(photos)) / months
+	v.PhotoLikesAvg = float64(likes) / float64(len(photos))
+	v.GeotaggedPhotoShare = float64(placed) / float64(len(photos))
+	v.LastPhotoAt = maxDate
+	return v
+}
+
+// profileCompleteness is the share of optional profile fields the user filled in.
+func profileCompleteness(u domain.VKUser) float64 {
+	filled := []bool{
+		u.BirthDate != "",
+		u.City != "",
+		u.About != "",
+		u.Interests != "",
+		u.Activities != "",
+		u.Books != "",
+		u.Movies != "",
+		u.Music != "",
+		u.Site != "",
+		u.Relation != 0,
+		u.Education.University != "" || len(u.Universities) > 0,
+		len(u.Career) > 0 || u.Occupation.Name != "",
+		len(u.Personal.Langs) > 0 || u.Personal.Political != 0 || u.Personal.LifeMain != 0,
+	}
+
+	n := 0
+	for _, ok := range filled {
+		if ok {
+			n++
+		}
 	}
+	return float64(n) / float64(len(filled))
 }
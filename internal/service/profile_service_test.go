@@ -2,26 +2,44 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/sony/gobreaker"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
+	"inteam/internal/config"
 	"inteam/internal/domain"
+	"inteam/internal/llm"
+	"inteam/internal/vk"
 )
 
 type vkClientMock struct {
-	user    *domain.VKUser
-	wall    []domain.WallPost
-	gifts   []domain.Gift
-	friends []domain.Friend
-	err     error
+	user        *domain.VKUser
+	wall        []domain.WallPost
+	gifts       []domain.Gift
+	friends     []domain.Friend
+	comments    []domain.Comment
+	community   *domain.Community
+	invalidated []int64
+	err         error
 }
 
 func (m *vkClientMock) GetUser(ctx context.Context, vkID int64) (*domain.VKUser, error) {
 	return m.user, m.err
 }
 
+func (m *vkClientMock) GetUsers(ctx context.Context, ids []int64, fields string) (*vk.UsersResult, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &vk.UsersResult{Missing: ids}, nil
+}
+
 func (m *vkClientMock) GetWall(ctx context.Context, vkID int64, offset, count int) ([]domain.WallPost, error) {
 	return m.wall, m.err
 }
@@ -34,13 +52,132 @@ func (m *vkClientMock) GetFriends(ctx context.Context, vkID int64, offset, count
 	return m.friends, m.err
 }
 
-type gigachatMock struct {
-	summary string
-	err     error
+func (m *vkClientMock) GetAllWall(ctx context.Context, vkID int64, limit int) ([]domain.WallPost, int, error) {
+	return m.wall, len(m.wall), m.err
+}
+
+func (m *vkClientMock) GetAllGifts(ctx context.Context, vkID int64, limit int) ([]domain.Gift, int, error) {
+	return m.gifts, len(m.gifts), m.err
+}
+
+func (m *vkClientMock) GetAllFriends(ctx context.Context, vkID int64, limit int) ([]domain.Friend, int, error) {
+	return m.friends, len(m.friends), m.err
+}
+
+func (m *vkClientMock) GetComments(ctx context.Context, ownerID, postID int64, limit int) ([]domain.Comment, error) {
+	return m.comments, m.err
+}
+
+func (m *vkClientMock) GetGroups(ctx context.Context, vkID int64, limit int) ([]domain.Community, int, error) {
+	return nil, 0, m.err
+}
+
+func (m *vkClientMock) GetSubscriptions(ctx context.Context, vkID int64, limit int) ([]domain.Community, int, error) {
+	return nil, 0, m.err
+}
+
+func (m *vkClientMock) GetMutualFriends(ctx context.Context, sourceID int64, targetIDs []int64) (map[int64][]int64, error) {
+	return nil, m.err
+}
+
+func (m *vkClientMock) GetFriendIDs(ctx context.Context, userIDs []int64, limit int) (map[int64][]int64, error) {
+	return nil, m.err
+}
+
+func (m *vkClientMock) GetPhotos(ctx context.Context, vkID int64, limit int) ([]domain.Photo, int, error) {
+	return nil, 0, m.err
+}
+
+func (m *vkClientMock) GetAlbums(ctx context.Context, vkID int64, limit int) ([]domain.Album, int, error) {
+	return nil, 0, m.err
+}
+
+func (m *vkClientMock) GetCommunity(ctx context.Context, groupID int64) (*domain.Community, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.community, nil
+}
+
+func (m *vkClientMock) GetProfileBundle(ctx context.Context, vkID int64, req vk.BundleRequest) (*vk.ProfileBundle, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &vk.ProfileBundle{
+		User:         m.user,
+		Wall:         m.wall,
+		Gifts:        m.gifts,
+		Friends:      m.friends,
+		WallTotal:    len(m.wall),
+		GiftsTotal:   len(m.gifts),
+		FriendsTotal: len(m.friends),
+	}, nil
+}
+
+func (m *vkClientMock) ResolveScreenName(ctx context.Context, screenName string) (int64, error) {
+	if m.user == nil {
+		return 0, m.err
+	}
+	return m.user.ID, m.err
+}
+
+func (m *vkClientMock) TokenStates() []vk.TokenState {
+	return nil
+}
+
+func (m *vkClientMock) BreakerState() gobreaker.State {
+	return gobreaker.StateClosed
+}
+
+func (m *vkClientMock) Invalidate(ctx context.Context, vkIDs ...int64) error {
+	m.invalidated = append(m.invalidated, vkIDs...)
+	return m.err
+}
+
+func (m *vkClientMock) ListenInvalidations(ctx context.Context) {}
+
+type summarizerMock struct {
+	summary     string
+	err         error
+	promptErr   error
+	insights    *domain.Insights
+	insightsErr error
 }
 
-func (g *gigachatMock) GenerateProfileSummary(ctx context.Context, data domain.ProfileData) (string, error) {
-	return g.summary, g.err
+func (g *summarizerMock) GenerateProfileSummary(ctx context.Context, data domain.ProfileData) (llm.Summary, error) {
+	return llm.Summary{Text: g.summary, PromptVersion: "profile-v1"}, g.err
+}
+
+func (g *summarizerMock) GenerateCommunitySummary(ctx context.Context, data domain.CommunityData) (llm.Summary, error) {
+	return llm.Summary{Text: g.summary, PromptVersion: "community-v1"}, g.err
+}
+
+func (g *summarizerMock) StreamProfileSummary(ctx context.Context, data domain.ProfileData, onDelta llm.DeltaFunc) (llm.Summary, error) {
+	if g.err != nil {
+		return llm.Summary{}, g.err
+	}
+	for _, word := range strings.SplitAfter(g.summary, " ") {
+		if err := onDelta(word); err != nil {
+			return llm.Summary{}, err
+		}
+	}
+	return llm.Summary{Text: g.summary, PromptVersion: "profile-v1"}, nil
+}
+
+func (g *summarizerMock) ExtractProfileInsights(ctx context.Context, data domain.ProfileData) (*domain.Insights, error) {
+	return g.insights, g.insightsErr
+}
+
+func (g *summarizerMock) CheckPrompt(ctx context.Context, kind llm.PromptKind) error {
+	return g.promptErr
+}
+
+func (g *summarizerMock) Name() string {
+	return "mock"
+}
+
+func (g *summarizerMock) BreakerState() gobreaker.State {
+	return gobreaker.StateClosed
 }
 
 type profileRepoMock struct {
@@ -69,12 +206,17 @@ func TestAnalyzeProfile_Basic(t *testing.T) {
 			{Text: "world", Date: time.Unix(86400, 0)},
 		},
 	}
-	ggMock := &gigachatMock{summary: "test summary"}
+	insights := &domain.Insights{
+		Interests:          []string{"походы"},
+		Traits:             []domain.Trait{{Name: "открытость", Confidence: 0.8}},
+		CommunicationStyle: "дружелюбный",
+	}
+	summarizer := &summarizerMock{summary: "test summary", insights: insights}
 	repoMock := &profileRepoMock{}
 
 	svc := &profileService{
 		vkClient:    vkMock,
-		gigachat:    ggMock,
+		summarizer:  summarizer,
 		profileRepo: repoMock,
 	}
 
@@ -82,6 +224,401 @@ func TestAnalyzeProfile_Basic(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, profile)
 	require.Equal(t, "test summary", profile.Summary)
+	require.Equal(t, "profile-v1", profile.PromptVersion)
+	require.Equal(t, insights, repoMock.saved.Insights)
 	require.NotEmpty(t, repoMock.saved.RawJSON)
 }
 
+func TestAnalyzeProfile_SavesSummaryWhenInsightsFail(t *testing.T) {
+	repoMock := &profileRepoMock{}
+	svc := &profileService{
+		vkClient:    &vkClientMock{user: &domain.VKUser{ID: 1, FirstName: "Test", LastName: "User"}},
+		summarizer:  &summarizerMock{summary: "test summary", insightsErr: llm.ErrInvalidInsights},
+		profileRepo: repoMock,
+		logger:      zap.NewNop(),
+	}
+
+	profile, err := svc.AnalyzeProfile(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "test summary", profile.Summary)
+	require.Nil(t, profile.Insights)
+	require.Same(t, profile, repoMock.saved)
+}
+
+// slowInsightsMock blocks until the insights deadline runs out.
+type slowInsightsMock struct {
+	summarizerMock
+}
+
+func (g *slowInsightsMock) ExtractProfileInsights(ctx context.Context, data domain.ProfileData) (*domain.Insights, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAnalyzeProfile_SavesSummaryWhenInsightsTimeOut(t *testing.T) {
+	repoMock := &profileRepoMock{}
+	svc := &profileService{
+		cfg:         config.AnalysisConfig{Timeout: time.Minute, InsightsTimeout: 10 * time.Millisecond},
+		vkClient:    &vkClientMock{user: &domain.VKUser{ID: 1, FirstName: "Test", LastName: "User"}},
+		summarizer:  &slowInsightsMock{summarizerMock{summary: "test summary"}},
+		profileRepo: repoMock,
+		logger:      zap.NewNop(),
+	}
+
+	profile, err := svc.AnalyzeProfile(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "test summary", profile.Summary)
+	require.Nil(t, profile.Insights)
+	require.Same(t, profile, repoMock.saved)
+}
+
+func TestAnalyzeProfile_RejectsUnknownPrompt(t *testing.T) {
+	repoMock := &profileRepoMock{}
+	svc := &profileService{
+		vkClient:    &vkClientMock{err: errors.New("vk must not be called")},
+		summarizer:  &summarizerMock{promptErr: fmt.Errorf("%w: profile prompt \"v9\"", llm.ErrUnknownPrompt)},
+		profileRepo: repoMock,
+	}
+
+	_, err := svc.AnalyzeProfile(context.Background(), 1)
+	var derr *domain.Error
+	require.ErrorAs(t, err, &derr)
+	require.ErrorIs(t, err, domain.ErrInvalidInput)
+	require.Equal(t, "unknown_prompt", derr.Code)
+	require.Nil(t, repoMock.saved)
+}
+
+func TestAnalyzeProfileStream_EmitsStagesAndSummary(t *testing.T) {
+	vkMock := &vkClientMock{user: &domain.VKUser{ID: 1, FirstName: "Test", LastName: "User"}}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		vkClient:    vkMock,
+		summarizer:  &summarizerMock{summary: "streamed test summary", insights: &domain.Insights{Topics: []string{"горы"}}},
+		profileRepo: repoMock,
+	}
+
+	var (
+		stages   []string
+		deltas   []string
+		insights *domain.Insights
+	)
+	profile, err := svc.AnalyzeProfileStream(context.Background(), 1, func(ev AnalysisEvent) error {
+		switch ev.Type {
+		case EventStage:
+			stages = append(stages, ev.Stage)
+		case EventFetched:
+			require.NotNil(t, ev.Vector)
+		case EventSummary:
+			deltas = append(deltas, ev.Delta)
+		case EventInsights:
+			insights = ev.Insights
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{stageFetch, stageSummarize, stagePersist}, stages)
+	require.Equal(t, []string{"streamed ", "test ", "summary"}, deltas)
+	require.Equal(t, "streamed test summary", profile.Summary)
+	require.Equal(t, "streamed test summary", repoMock.saved.Summary)
+	require.Equal(t, []string{"горы"}, insights.Topics)
+}
+
+func TestAnalyzeProfile_MapsVKErrors(t *testing.T) {
+	vkMock := &vkClientMock{err: &vk.APIError{Code: 30, Message: "This profile is private"}}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		vkClient:    vkMock,
+		summarizer:  &summarizerMock{},
+		profileRepo: repoMock,
+	}
+
+	_, err := svc.AnalyzeProfile(context.Background(), 1)
+	require.ErrorIs(t, err, domain.ErrForbidden)
+	require.ErrorIs(t, err, vk.ErrProfilePrivate)
+
+	var derr *domain.Error
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, "profile_private", derr.Code)
+	require.Nil(t, repoMock.saved)
+}
+
+// slowSummarizerMock blocks until the summary deadline runs out.
+type slowSummarizerMock struct {
+	summarizerMock
+}
+
+func (g *slowSummarizerMock) GenerateProfileSummary(ctx context.Context, data domain.ProfileData) (llm.Summary, error) {
+	<-ctx.Done()
+	return llm.Summary{}, ctx.Err()
+}
+
+func (g *slowSummarizerMock) GenerateCommunitySummary(ctx context.Context, data domain.CommunityData) (llm.Summary, error) {
+	<-ctx.Done()
+	return llm.Summary{}, ctx.Err()
+}
+
+func TestAnalyzeProfile_ReportsTimedOutStage(t *testing.T) {
+	vkMock := &vkClientMock{user: &domain.VKUser{ID: 1, FirstName: "Test", LastName: "User"}}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		cfg:         config.AnalysisConfig{Timeout: time.Minute, SummarizeTimeout: 10 * time.Millisecond},
+		vkClient:    vkMock,
+		summarizer:  &slowSummarizerMock{},
+		profileRepo: repoMock,
+	}
+
+	_, err := svc.AnalyzeProfile(context.Background(), 1)
+	require.ErrorIs(t, err, domain.ErrTimeout)
+
+	var derr *domain.Error
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, "summarize_timeout", derr.Code)
+	require.Nil(t, repoMock.saved)
+}
+
+type bundleVKClientMock struct {
+	vkClientMock
+	bundle *vk.ProfileBundle
+}
+
+func (m *bundleVKClientMock) GetProfileBundle(ctx context.Context, vkID int64, req vk.BundleRequest) (*vk.ProfileBundle, error) {
+	return m.bundle, nil
+}
+
+func TestAnalyzeProfile_PartialWhenWallClosed(t *testing.T) {
+	vkMock := &bundleVKClientMock{
+		bundle: &vk.ProfileBundle{
+			User:    &domain.VKUser{ID: 1, FirstName: "Test", LastName: "User"},
+			Friends: []domain.Friend{{ID: 2}},
+			Errors: map[domain.Source]error{
+				domain.SourceWall: &vk.APIError{Code: 30, Message: "This profile is private"},
+			},
+		},
+	}
+	summarizer := &summarizerMock{summary: "partial summary"}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		vkClient:    vkMock,
+		summarizer:  summarizer,
+		profileRepo: repoMock,
+	}
+
+	profile, err := svc.AnalyzeProfile(context.Background(), 1)
+	require.NoError(t, err)
+	require.True(t, profile.Partial)
+	require.Contains(t, repoMock.saved.RawJSON, `"Code":"profile_private"`)
+
+	vkMock.bundle.Errors[domain.SourceWall] = &vk.APIError{Code: 10, Message: "Internal server error"}
+	_, err = svc.AnalyzeProfile(context.Background(), 1)
+	require.ErrorIs(t, err, domain.ErrUpstream)
+}
+
+func TestAnalyzeProfile_NotFoundWithoutUser(t *testing.T) {
+	vkMock := &bundleVKClientMock{bundle: &vk.ProfileBundle{}}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		vkClient:    vkMock,
+		summarizer:  &summarizerMock{},
+		profileRepo: repoMock,
+	}
+
+	_, err := svc.AnalyzeProfile(context.Background(), 1)
+	require.ErrorIs(t, err, domain.ErrNotFound)
+	require.Nil(t, repoMock.saved)
+}
+
+func TestInterestDistribution(t *testing.T) {
+	shares := interestDistribution(
+		[]domain.Community{
+			{ID: 1, Activity: "Музыка"},
+			{ID: 2, Activity: "Компьютерные игры"},
+			{ID: 3, Activity: "Музыкант"},
+		},
+		[]domain.Community{
+			{ID: 1, Activity: "Музыка"},
+			{ID: 4, Activity: "Открытая группа"},
+		},
+	)
+
+	require.Len(t, shares, 3)
+	require.Equal(t, "Музыка", shares[0].Category)
+	require.Equal(t, 2, shares[0].Count)
+	require.InDelta(t, 0.5, shares[0].Share, 1e-9)
+	require.Equal(t, "Другое", shares[1].Category)
+	require.Equal(t, "Игры", shares[2].Category)
+}
+
+func TestInterestCategory(t *testing.T) {
+	cases := map[string]string{
+		"Музыка":            "Музыка",
+		"Компьютерные игры": "Игры",
+		"IT":                "IT и технологии",
+		"Мода":              "Красота и мода",
+		"Модная одежда":     "Красота и мода",
+		"Бег":               "Спорт и фитнес",
+		"Детские товары":    "Семья и дети",
+		"Автомобили":        "Авто и мото",
+		"Italian food":      "Другое",
+		"Модели и моделирование": "Другое",
+		"Детективы":              "Другое",
+		"Бегемоты":               "Другое",
+		"Игрушки":                "Другое",
+		"Авторская песня":        "Другое",
+		"Кафедра":                "Другое",
+		"Кинология":              "Другое",
+	}
+	for activity, want := range cases {
+		require.Equal(t, want, interestCategory(activity), activity)
+	}
+}
+
+func TestBuildActivityVector_RepostRatio(t *testing.T) {
+	data := domain.ProfileData{
+		Wall: []domain.WallPost{
+			{Text: "hello world", Date: time.Unix(0, 0)},
+			{Text: "", Date: time.Unix(86400, 0), CopyHistory: []domain.RepostedPost{{ID: 1, OwnerID: -1}}},
+			{Text: "abc", Date: time.Unix(2*86400, 0), Attachments: []domain.Attachment{{Type: "photo"}}},
+			{Text: "", Date: time.Unix(3*86400, 0), CopyHistory: []domain.RepostedPost{{ID: 2, OwnerID: -2}}},
+		},
+	}
+
+	v := buildActivityVector(data)
+	require.Equal(t, 2, v.OriginalPostsCount)
+	require.Equal(t, 2, v.RepostsCount)
+	require.InDelta(t, 0.5, v.RepostRatio, 1e-9)
+	require.InDelta(t, 7.0, v.AveragePostLen, 1e-9)
+	require.InDelta(t, 0.25, v.AttachmentsPerPost, 1e-9)
+}
+
+func TestBuildAudienceMetrics(t *testing.T) {
+	const owner = 1
+	comments := []domain.Comment{
+		{ID: 10, FromID: 2},
+		{ID: 11, FromID: owner, ParentID: 10, ReplyToComment: 10},
+		{ID: 12, FromID: 3},
+		{ID: 13, FromID: 2, ParentID: 12},
+		{ID: 14, FromID: 4},
+	}
+	friends := []domain.Friend{{ID: 2, FirstName: "Ivan", LastName: "Petrov"}}
+
+	m := buildAudienceMetrics(owner, 2, comments, friends)
+	require.Equal(t, 5, m.CommentsAnalyzed)
+	require.Equal(t, 3, m.UniqueCommenters)
+	require.InDelta(t, 0.5, m.FriendCommentShare, 1e-9)
+	require.InDelta(t, 0.25, m.OwnerReplyRate, 1e-9)
+	require.Equal(t, int64(2), m.TopCommenters[0].ID)
+	require.Equal(t, "Ivan Petrov", m.TopCommenters[0].Name)
+	require.True(t, m.TopCommenters[0].IsFriend)
+}
+
+func TestBuildActivityVector_PhotoCadence(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := domain.ProfileData{
+		Photos: []domain.Photo{
+			{Date: start, Likes: 10, Lat: 55.75, Long: 37.61},
+			{Date: start.AddDate(0, 0, 60), Likes: 20},
+			{Date: start.AddDate(0, 0, 120), Likes: 30},
+		},
+		PhotosTotal: 40,
+		Albums:      []domain.Album{{ID: 1, Title: "Trip"}},
+	}
+
+	v := buildActivityVector(data)
+	require.Equal(t, 40, v.PhotosCount)
+	require.Equal(t, 1, v.AlbumsCount)
+	require.InDelta(t, 0.75, v.PhotosPerMonth, 1e-9)
+	require.InDelta(t, 20, v.PhotoLikesAvg, 1e-9)
+	require.InDelta(t, 1.0/3, v.GeotaggedPhotoShare, 1e-9)
+	require.Equal(t, start.AddDate(0, 0, 120), v.LastPhotoAt)
+}
+
+func TestNetworkMetrics(t *testing.T) {
+	const owner = 1
+	friends := []domain.Friend{{ID: 2}, {ID: 3}, {ID: 4}, {ID: 5, Deactivated: "deleted"}}
+	// 2-3-4 form a triangle seen from both ends; 9 is not a friend of the owner.
+	mutual := map[int64][]int64{
+		2: {3, 4},
+		3: {2, 4, 9},
+		4: {2, 3},
+	}
+
+	network := buildEgoNetwork(owner, []int64{2, 3, 4}, mutual)
+	require.Equal(t, [][2]int64{{2, 3}, {2, 4}, {3, 4}}, network.Edges)
+
+	lists := map[int64][]int64{
+		2: {1, 3, 10, 11},
+		3: {1, 2, 11, 12},
+	}
+	m := networkMetrics(friends, network, lists, owner)
+	require.Equal(t, 3, m.FriendsAnalyzed)
+	require.Equal(t, 3, m.Edges)
+	require.InDelta(t, 1, m.Density, 1e-9)
+	require.InDelta(t, 1, m.ClusteringCoefficient, 1e-9)
+	require.Equal(t, 3, m.FriendOfFriendReach)
+	require.Equal(t, 2, m.ReachSampled)
+	require.InDelta(t, 0.25, m.DeactivatedShare, 1e-9)
+}
+
+func TestAnalyzeCommunity_StoresNegativeOwnerID(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	vkMock := &vkClientMock{
+		community: &domain.Community{ID: 42, Name: "Go Club", ScreenName: "goclub", Type: "group", MembersCount: 1000},
+		wall: []domain.WallPost{
+			{Text: "hello", Date: start, Likes: 10, Views: 200},
+			{Text: "world", Date: start.AddDate(0, 2, 0), Likes: 30, Comments: 10, Views: 400},
+		},
+	}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		vkClient:    vkMock,
+		summarizer:  &summarizerMock{summary: "community summary"},
+		profileRepo: repoMock,
+	}
+
+	profile, err := svc.AnalyzeCommunity(context.Background(), 42)
+	require.NoError(t, err)
+	require.Equal(t, int64(-42), profile.VKID)
+	require.Equal(t, "Go Club", profile.FullName)
+	require.Equal(t, "community summary", profile.Summary)
+
+	v := buildCommunityVector(domain.CommunityData{Community: *vkMock.community, Wall: vkMock.wall})
+	require.InDelta(t, 25, v.EngagementRate, 1e-9)
+	require.InDelta(t, 0.025, v.EngagementPerMember, 1e-9)
+	require.InDelta(t, 0.3, v.ReachRate, 1e-9)
+}
+
+func TestAnalyzeCommunity_HonoursBudget(t *testing.T) {
+	vkMock := &vkClientMock{community: &domain.Community{ID: 42, Name: "Go Club"}}
+	repoMock := &profileRepoMock{}
+
+	svc := &profileService{
+		cfg:         config.AnalysisConfig{Timeout: 10 * time.Millisecond},
+		vkClient:    vkMock,
+		summarizer:  &slowSummarizerMock{},
+		profileRepo: repoMock,
+	}
+
+	_, err := svc.AnalyzeCommunity(context.Background(), 42)
+	require.ErrorIs(t, err, domain.ErrTimeout)
+
+	var derr *domain.Error
+	require.ErrorAs(t, err, &derr)
+	require.Equal(t, "summarize_timeout", derr.Code)
+	require.Nil(t, repoMock.saved)
+}
+
+func TestCacheService_WarmPurgesAndSkipsCommunities(t *testing.T) {
+	vkMock := &vkClientMock{}
+	svc := NewCacheService(vkMock, zap.NewNop())
+
+	res, err := svc.Warm(context.Background(), []int64{1, -42, 2})
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, -42, 2}, vkMock.invalidated)
+	require.Equal(t, []int64{1, 2}, res.Missing)
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"inteam/internal/domain"
+)
+
+// Analysis stages, each with its own deadline within the overall budget.
+const (
+	stageFetch     = "fetch"
+	stageSummarize = "summarize"
+	stagePersist   = "persist"
+)
+
+// Event types of a streamed analysis.
+const (
+	// EventStage marks the start of a stage.
+	EventStage = "stage"
+	// EventFetched reports what was collected from VK.
+	EventFetched = "fetched"
+	// EventSummary carries the next chunk of the summary.
+	EventSummary = "summary"
+	// EventInsights carries the structured insights once they are extracted.
+	EventInsights = "insights"
+)
+
+// AnalysisEvent reports the progress of a streamed analysis.
+type AnalysisEvent struct {
+	Type        string                 `json:"type"`
+	Stage       string                 `json:"stage,omitempty"`
+	Delta       string                 `json:"delta,omitempty"`
+	Vector      *domain.ActivityVector `json:"vector,omitempty"`
+	Unavailable []domain.SourceIssue   `json:"unavailable,omitempty"`
+	Partial     bool                   `json:"partial,omitempty"`
+	Insights    *domain.Insights       `json:"insights,omitempty"`
+}
+
+func fetchedEvent(data domain.ProfileData) AnalysisEvent {
+	vector := data.Vector
+	return AnalysisEvent{
+		Type:        EventFetched,
+		Vector:      &vector,
+		Unavailable: data.Unavailable,
+		Partial:     data.Partial,
+	}
+}
+
+// withBudget limits the whole analysis to cfg.Timeout.
+func (s *profileService) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.cfg.Timeout)
+}
+
+// runStage runs fn with the stage timeout and names the stage if it times out.
+func runStage(ctx context.Context, stage string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	stageCtx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		stageCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	err := fn(stageCtx)
+	if err != nil && errors.Is(stageCtx.Err(), context.DeadlineExceeded) {
+		return &domain.Error{
+			Kind:    domain.ErrTimeout,
+			Code:    stage + "_timeout",
+			Message: fmt.Sprintf("profile analysis timed out at the %s stage", stage),
+			Err:     err,
+		}
+	}
+	return err
+}
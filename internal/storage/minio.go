@@ -15,6 +15,7 @@ import (
 
 type ObjectStorage interface {
 	SaveProfileSnapshot(ctx context.Context, vkID int64, raw []byte) error
+	SaveEgoNetwork(ctx context.Context, vkID int64, raw []byte) error
 }
 
 type minioStorage struct {
@@ -58,16 +59,23 @@ func NewMinio(cfg config.MinioConfig, logger *zap.Logger) (ObjectStorage, error)
 }
 
 func (s *minioStorage) SaveProfileSnapshot(ctx context.Context, vkID int64, raw []byte) error {
-	objectName := fmt.Sprintf("profiles/%s.json", strconv.FormatInt(vkID, 10))
+	return s.putJSON(ctx, fmt.Sprintf("profiles/%s.json", strconv.FormatInt(vkID, 10)), raw)
+}
+
+// SaveEgoNetwork stores the friend graph separately from the profile
+// snapshot: it can be orders of magnitude larger.
+func (s *minioStorage) SaveEgoNetwork(ctx context.Context, vkID int64, raw []byte) error {
+	return s.putJSON(ctx, fmt.Sprintf("networks/%s.json", strconv.FormatInt(vkID, 10)), raw)
+}
 
+func (s *minioStorage) putJSON(ctx context.Context, objectName string, raw []byte) error {
 	_, err := s.client.PutObject(ctx, s.bucket, objectName, bytes.NewReader(raw), int64(len(raw)), minio.PutObjectOptions{
 		ContentType: "application/json",
 	})
 	if err != nil {
-		s.logger.Warn("failed to save profile snapshot", zap.Error(err))
+		s.logger.Warn("failed to save object", zap.String("object", objectName), zap.Error(err))
 		return err
 	}
 
 	return nil
 }
-
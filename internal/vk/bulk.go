@@ -0,0 +1,101 @@
+package vk
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"inteam/internal/domain"
+)
+
+// users.get accepts up to 1000 user_ids per call.
+const usersPerCall = 1000
+
+// UsersResult is the outcome of a bulk users.get. Users keeps the order of
+// the requested IDs; deactivated users are reported by ID only.
+type UsersResult struct {
+	Users       []*domain.VKUser
+	Missing     []int64
+	Deactivated []int64
+}
+
+// GetUsers fetches many users with as few requests as possible. An empty
+// fields value requests the full profile field set; only such users are
+// served from and written to the cache, so GetUser never returns a user
+// with partial fields.
+func (c *client) GetUsers(ctx context.Context, ids []int64, fields string) (*UsersResult, error) {
+	full := fields == "" || fields == userFields
+	if fields == "" {
+		fields = userFields
+	}
+
+	found := make(map[int64]*domain.VKUser)
+	deactivated := make(map[int64]bool)
+
+	var pending []int64
+	seen := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		pending = append(pending, id)
+	}
+	if full {
+		found, pending = cachedByID[*domain.VKUser](ctx, c, pending, c.cfg.Cache.Users, func(id int64) string {
+			return cacheKey(id, "users.get")
+		})
+	}
+
+	for start := 0; start < len(pending); start += usersPerCall {
+		end := min(start+usersPerCall, len(pending))
+
+		params := url.Values{}
+		params.Set("user_ids", joinIDs(pending[start:end]))
+		params.Set("fields", fields)
+
+		var users []apiUser
+		if err := c.callVK(ctx, "users.get", params, &users); err != nil {
+			return nil, err
+		}
+
+		for _, u := range users {
+			if u.Deactivated != "" {
+				deactivated[u.ID] = true
+				continue
+			}
+			user := u.toDomain()
+			found[user.ID] = user
+			if full {
+				c.storeUser(ctx, user)
+			}
+		}
+	}
+
+	result := &UsersResult{Users: make([]*domain.VKUser, 0, len(found))}
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			continue
+		}
+		delete(seen, id)
+
+		switch {
+		case found[id] != nil:
+			result.Users = append(result.Users, found[id])
+		case deactivated[id]:
+			result.Deactivated = append(result.Deactivated, id)
+		default:
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result, nil
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
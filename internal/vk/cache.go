@@ -0,0 +1,209 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Cached values are prefixed with a marker byte: valueEntry for JSON encoded
+// results and notFoundEntry for negative results, followed by the error kind.
+const (
+	valueEntry    = 'v'
+	notFoundEntry = 'n'
+)
+
+var negativeKinds = map[string]error{
+	"not_found":    ErrNotFound,
+	"user_deleted": ErrUserDeleted,
+}
+
+// cacheKey builds keys of the form vk:<owner id>:<method>[:<args>...], so
+// that everything cached for one user or community shares a prefix.
+func cacheKey(ownerID int64, method string, args ...interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "vk:%d:%s", ownerID, method)
+	for _, arg := range args {
+		fmt.Fprintf(&b, ":%v", arg)
+	}
+	return b.String()
+}
+
+// cacheable lets a value opt out of caching, e.g. a bundle that carries a
+// transient per-source error.
+type cacheable interface {
+	cacheable() bool
+}
+
+// cached returns the value stored under key or loads it with fetch, sharing
+// one fetch between concurrent callers.
+func cached[T any](ctx context.Context, c *client, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	if ttl > 0 {
+		if raw, ok := c.cache.Get(ctx, key); ok {
+			if v, err, ok := decodeEntry[T](raw); ok {
+				return v, err
+			}
+		}
+	}
+
+	type result struct {
+		v   interface{}
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err, _ := c.group.Do(key, func() (interface{}, error) {
+			ctx, cancel := detach(ctx, c.cfg.OperationTimeout)
+			defer cancel()
+
+			v, err := fetch(ctx)
+			if err != nil {
+				if ttl > 0 {
+					c.storeNegative(ctx, key, err)
+				}
+				return nil, err
+			}
+			if ttl > 0 {
+				c.storeValue(ctx, key, v, ttl)
+			}
+			return v, nil
+		})
+		done <- result{v, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case r := <-done:
+		if r.err != nil {
+			return zero, r.err
+		}
+		return r.v.(T), nil
+	}
+}
+
+// detach returns a context that is not canceled with ctx but keeps its
+// deadline, or gets timeout when ctx has none.
+func detach(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	if timeout > 0 {
+		return context.WithTimeout(detached, timeout)
+	}
+	return detached, func() {}
+}
+
+func decodeEntry[T any](raw []byte) (T, error, bool) {
+	var v T
+	if len(raw) == 0 {
+		return v, nil, false
+	}
+
+	switch raw[0] {
+	case valueEntry:
+		if err := json.Unmarshal(raw[1:], &v); err != nil {
+			return v, nil, false
+		}
+		return v, nil, true
+	case notFoundEntry:
+		kind, ok := negativeKinds[string(raw[1:])]
+		if !ok {
+			return v, nil, false
+		}
+		return v, fmt.Errorf("%w (cached)", kind), true
+	}
+	return v, nil, false
+}
+
+func (c *client) storeValue(ctx context.Context, key string, v interface{}, ttl time.Duration) {
+	if cv, ok := v.(cacheable); ok && !cv.cacheable() {
+		return
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		c.logger.Warn("vk cache: failed to encode value", zap.String("key", key), zap.Error(err))
+		return
+	}
+	c.cache.Set(ctx, key, append([]byte{valueEntry}, raw...), ttl)
+}
+
+func (c *client) storeNegative(ctx context.Context, key string, err error) {
+	if c.cfg.Cache.NegativeTTL <= 0 || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	for kind, sentinel := range negativeKinds {
+		if errors.Is(err, sentinel) {
+			c.cache.Set(ctx, key, append([]byte{notFoundEntry}, kind...), c.cfg.Cache.NegativeTTL)
+			return
+		}
+	}
+}
+
+type page[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+// cachedPage is cached for fetchAll-style results that return items
+// together with the total reported by VK.
+func cachedPage[T any](ctx context.Context, c *client, key string, ttl time.Duration, fetch func(ctx context.Context) ([]T, int, error)) ([]T, int, error) {
+	p, err := cached(ctx, c, key, ttl, func(ctx context.Context) (page[T], error) {
+		items, total, err := fetch(ctx)
+		return page[T]{Items: items, Total: total}, err
+	})
+	return p.Items, p.Total, err
+}
+
+// cachedByID looks up one cache entry per ID and returns the cached values
+// together with the IDs that still have to be fetched.
+func cachedByID[T any](ctx context.Context, c *client, ids []int64, ttl time.Duration, key func(id int64) string) (map[int64]T, []int64) {
+	found := make(map[int64]T, len(ids))
+	if ttl <= 0 {
+		return found, ids
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = key(id)
+	}
+	hits := c.cache.GetMulti(ctx, keys)
+
+	var rest []int64
+	for i, id := range ids {
+		if raw, ok := hits[keys[i]]; ok {
+			if v, err, ok := decodeEntry[T](raw); ok && err == nil {
+				found[id] = v
+				continue
+			}
+		}
+		rest = append(rest, id)
+	}
+	return found, rest
+}
+
+// Invalidate drops everything cached for the given users or communities
+// (negative IDs) on this instance and in Redis, and notifies the other
+// instances to drop their in-memory copies.
+func (c *client) Invalidate(ctx context.Context, vkIDs ...int64) error {
+	prefixes := make([]string, len(vkIDs))
+	for i, id := range vkIDs {
+		prefixes[i] = fmt.Sprintf("vk:%d:", id)
+	}
+	return c.invalidator.Invalidate(ctx, prefixes...)
+}
+
+// ListenInvalidations applies invalidations from other instances until ctx
+// is done. It returns immediately when Redis is not configured.
+func (c *client) ListenInvalidations(ctx context.Context) {
+	c.invalidator.Listen(ctx)
+}
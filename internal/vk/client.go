@@ -7,48 +7,95 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/sony/gobreaker"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 
+	"inteam/internal/breaker"
+	"inteam/internal/cache"
 	"inteam/internal/config"
 	"inteam/internal/domain"
+	"inteam/internal/retry"
 )
 
 type Client interface {
 	GetUser(ctx context.Context, vkID int64) (*domain.VKUser, error)
+	GetUsers(ctx context.Context, ids []int64, fields string) (*UsersResult, error)
 	GetWall(ctx context.Context, vkID int64, offset, count int) ([]domain.WallPost, error)
 	GetGifts(ctx context.Context, vkID int64, offset, count int) ([]domain.Gift, error)
 	GetFriends(ctx context.Context, vkID int64, offset, count int) ([]domain.Friend, error)
+	GetAllWall(ctx context.Context, vkID int64, limit int) ([]domain.WallPost, int, error)
+	GetAllGifts(ctx context.Context, vkID int64, limit int) ([]domain.Gift, int, error)
+	GetAllFriends(ctx context.Context, vkID int64, limit int) ([]domain.Friend, int, error)
+	GetComments(ctx context.Context, ownerID, postID int64, limit int) ([]domain.Comment, error)
+	GetGroups(ctx context.Context, vkID int64, limit int) ([]domain.Community, int, error)
+	GetSubscriptions(ctx context.Context, vkID int64, limit int) ([]domain.Community, int, error)
+	GetCommunity(ctx context.Context, groupID int64) (*domain.Community, error)
+	GetMutualFriends(ctx context.Context, sourceID int64, targetIDs []int64) (map[int64][]int64, error)
+	GetFriendIDs(ctx context.Context, userIDs []int64, limit int) (map[int64][]int64, error)
+	GetPhotos(ctx context.Context, vkID int64, limit int) ([]domain.Photo, int, error)
+	GetAlbums(ctx context.Context, vkID int64, limit int) ([]domain.Album, int, error)
+	GetProfileBundle(ctx context.Context, vkID int64, req BundleRequest) (*ProfileBundle, error)
+	ResolveScreenName(ctx context.Context, screenName string) (int64, error)
+	TokenStates() []TokenState
+	BreakerState() gobreaker.State
+	Invalidate(ctx context.Context, vkIDs ...int64) error
+	ListenInvalidations(ctx context.Context)
 }
 
 type client struct {
-	cfg        config.VKConfig
-	httpClient *http.Client
-	logger     *zap.Logger
-	cache      sync.Map
-	redis      redis.UniversalClient
-	cb         *gobreaker.CircuitBreaker
+	cfg         config.VKConfig
+	httpClient  *http.Client
+	logger      *zap.Logger
+	cache       cache.Cache
+	group       singleflight.Group
+	invalidator *cache.Invalidator
+	redis       redis.UniversalClient
+	limiter     RateLimiter
+	tokens      *tokenPool
+	retry       retry.Policy
+	cb          *gobreaker.CircuitBreaker
 }
 
+const defaultLocalCacheSize = 10000
+
+// NewClient caches responses in an in-memory LRU in front of Redis (when
+// redisClient is set).
 func NewClient(cfg config.VKConfig, httpClient *http.Client, logger *zap.Logger, redisClient redis.UniversalClient) Client {
+	size := cfg.Cache.LocalSize
+	if size == 0 {
+		size = defaultLocalCacheSize
+	}
+	local := cache.NewLRU(size, cfg.Cache.LocalTTL)
+	return NewClientWithCache(cfg, httpClient, logger, redisClient, cache.NewTiered(local, cache.NewRedisCache(redisClient), cfg.Cache.LocalTTL))
+}
+
+// NewClientWithCache is NewClient with a caller-provided response cache.
+func NewClientWithCache(cfg config.VKConfig, httpClient *http.Client, logger *zap.Logger, redisClient redis.UniversalClient, responseCache cache.Cache) Client {
+	var limiter RateLimiter
+	if cfg.RateLimit > 0 {
+		if redisClient != nil {
+			limiter = NewRedisRateLimiter(redisClient, cfg.RateLimit, cfg.RateBurst, logger)
+		} else {
+			limiter = NewLocalRateLimiter(cfg.RateLimit, cfg.RateBurst)
+		}
+	}
+
 	return &client{
-		cfg:        cfg,
-		httpClient: httpClient,
-		logger:     logger,
-		redis:      redisClient,
-		cb: gobreaker.NewCircuitBreaker(gobreaker.Settings{
-			Name:        "vk_api",
-			MaxRequests: 5,
-			Interval:    30 * time.Second,
-			Timeout:     30 * time.Second,
-			ReadyToTrip: func(counts gobreaker.Counts) bool {
-				return counts.ConsecutiveFailures >= 5
-			},
-		}),
+		cfg:         cfg,
+		httpClient:  httpClient,
+		logger:      logger,
+		cache:       responseCache,
+		invalidator: cache.NewInvalidator(responseCache, redisClient, logger),
+		redis:       redisClient,
+		limiter:     limiter,
+		tokens:      newTokenPool(append([]string{cfg.AccessToken}, cfg.AccessTokens...), cfg.TokenQuarantine, cfg.ThrottleThreshold, logger),
+		retry:       retry.FromConfig(cfg.Retry),
+		cb:          breaker.New("vk_api", cfg.Breaker, logger, isUpstreamFailure),
 	}
 }
 
@@ -58,129 +105,131 @@ type vkResponse struct {
 		ErrorCode int    `json:"error_code"`
 		ErrorMsg  string `json:"error_msg"`
 	} `json:"error,omitempty"`
+	ExecuteErrors []executeError `json:"execute_errors,omitempty"`
 }
 
 func (c *client) GetUser(ctx context.Context, vkID int64) (*domain.VKUser, error) {
-	cacheKey := fmt.Sprintf("user:%d", vkID)
-
-	if c.redis != nil {
-		if val, err := c.redis.Get(ctx, cacheKey).Result(); err == nil {
-			var user domain.VKUser
-			if err := json.Unmarshal([]byte(val), &user); err == nil {
-				return &user, nil
-			}
+	return cached(ctx, c, cacheKey(vkID, "users.get"), c.cfg.Cache.Users, func(ctx context.Context) (*domain.VKUser, error) {
+		var raw json.RawMessage
+		if err := c.callVK(ctx, "users.get", userParams(vkID), &raw); err != nil {
+			return nil, err
 		}
-	}
+		return decodeUser(raw)
+	})
+}
 
-	if val, ok := c.cache.Load(cacheKey); ok {
-		if user, ok := val.(*domain.VKUser); ok {
-			return user, nil
-		}
+// storeUser caches a user fetched by another method (bundle, bulk lookup)
+// so that later GetUser calls are served from the cache.
+func (c *client) storeUser(ctx context.Context, user *domain.VKUser) {
+	if c.cfg.Cache.Users > 0 {
+		c.storeValue(ctx, cacheKey(user.ID, "users.get"), user, c.cfg.Cache.Users)
 	}
+}
 
+func userParams(vkID int64) url.Values {
 	params := url.Values{}
 	params.Set("user_ids", strconv.FormatInt(vkID, 10))
-	params.Set("fields", "bdate,city,about,sex,screen_name")
-
-	var users []struct {
-		ID         int64  `json:"id"`
-		ScreenName string `json:"screen_name"`
-		FirstName  string `json:"first_name"`
-		LastName   string `json:"last_name"`
-		Sex        int    `json:"sex"`
-		BDate      string `json:"bdate"`
-		City       struct {
-			Title string `json:"title"`
-		} `json:"city"`
-		About string `json:"about"`
-	}
-
-	if err := c.callVK(ctx, "users.get", params, &users); err != nil {
+	params.Set("fields", userFields)
+	return params
+}
+
+func decodeUser(raw json.RawMessage) (*domain.VKUser, error) {
+	var users []apiUser
+	if err := json.Unmarshal(raw, &users); err != nil {
 		return nil, err
 	}
 
 	if len(users) == 0 {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrNotFound
 	}
 
 	u := users[0]
-	user := &domain.VKUser{
-		ID:         u.ID,
-		ScreenName: u.ScreenName,
-		FirstName:  u.FirstName,
-		LastName:   u.LastName,
-		Sex:        u.Sex,
-		BirthDate:  u.BDate,
-		City:       u.City.Title,
-		About:      u.About,
-	}
-
-	c.cache.Store(cacheKey, user)
-
-	if c.redis != nil {
-		if b, err := json.Marshal(user); err == nil {
-			_ = c.redis.Set(ctx, cacheKey, b, 10*time.Minute).Err()
-		}
+	if u.Deactivated != "" {
+		return nil, fmt.Errorf("%w: %s", ErrUserDeleted, u.Deactivated)
 	}
-	return user, nil
+	return u.toDomain(), nil
 }
 
 func (c *client) GetWall(ctx context.Context, vkID int64, offset, count int) ([]domain.WallPost, error) {
+	posts, _, err := cachedPage(ctx, c, cacheKey(vkID, "wall.get", offset, count), c.cfg.Cache.Wall, func(ctx context.Context) ([]domain.WallPost, int, error) {
+		return c.wallPage(ctx, vkID, offset, count)
+	})
+	return posts, err
+}
+
+func (c *client) GetAllWall(ctx context.Context, vkID int64, limit int) ([]domain.WallPost, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "wall.get", "all", limit), c.cfg.Cache.Wall, func(ctx context.Context) ([]domain.WallPost, int, error) {
+		return fetchAll(ctx, wallPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.WallPost, int, error) {
+			return c.wallPage(ctx, vkID, offset, count)
+		})
+	})
+}
+
+func (c *client) wallPage(ctx context.Context, vkID int64, offset, count int) ([]domain.WallPost, int, error) {
+	var raw json.RawMessage
+	if err := c.callVK(ctx, "wall.get", wallParams(vkID, offset, count), &raw); err != nil {
+		return nil, 0, err
+	}
+	return decodeWallPage(raw)
+}
+
+func wallParams(vkID int64, offset, count int) url.Values {
 	params := url.Values{}
 	params.Set("owner_id", strconv.FormatInt(vkID, 10))
 	params.Set("offset", strconv.Itoa(offset))
 	params.Set("count", strconv.Itoa(count))
+	return params
+}
 
+func decodeWallPage(raw json.RawMessage) ([]domain.WallPost, int, error) {
 	var resp struct {
-		Count int `json:"count"`
-		Items []struct {
-			ID       int64  `json:"id"`
-			Date     int64  `json:"date"`
-			Text     string `json:"text"`
-			Likes    struct {
-				Count int `json:"count"`
-			} `json:"likes"`
-			Reposts struct {
-				Count int `json:"count"`
-			} `json:"reposts"`
-			Comments struct {
-				Count int `json:"count"`
-			} `json:"comments"`
-			Views struct {
-				Count int `json:"count"`
-			} `json:"views"`
-			PostType string `json:"post_type"`
-			IsPinned int    `json:"is_pinned"`
-		} `json:"items"`
+		Count int           `json:"count"`
+		Items []apiWallPost `json:"items"`
 	}
 
-	if err := c.callVK(ctx, "wall.get", params, &resp); err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, 0, err
 	}
 
 	posts := make([]domain.WallPost, 0, len(resp.Items))
 	for _, p := range resp.Items {
-		posts = append(posts, domain.WallPost{
-			ID:       p.ID,
-			Date:     time.Unix(p.Date, 0),
-			Text:     p.Text,
-			Likes:    p.Likes.Count,
-			Reposts:  p.Reposts.Count,
-			Comments: p.Comments.Count,
-			Views:    p.Views.Count,
-			PostType: p.PostType,
-			IsPinned: p.IsPinned == 1,
-		})
+		posts = append(posts, p.toDomain())
 	}
-	return posts, nil
+	return posts, resp.Count, nil
 }
 
 func (c *client) GetGifts(ctx context.Context, vkID int64, offset, count int) ([]domain.Gift, error) {
+	gifts, _, err := cachedPage(ctx, c, cacheKey(vkID, "gifts.get", offset, count), c.cfg.Cache.Gifts, func(ctx context.Context) ([]domain.Gift, int, error) {
+		return c.giftsPage(ctx, vkID, offset, count)
+	})
+	return gifts, err
+}
+
+func (c *client) GetAllGifts(ctx context.Context, vkID int64, limit int) ([]domain.Gift, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "gifts.get", "all", limit), c.cfg.Cache.Gifts, func(ctx context.Context) ([]domain.Gift, int, error) {
+		return fetchAll(ctx, giftsPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.Gift, int, error) {
+			return c.giftsPage(ctx, vkID, offset, count)
+		})
+	})
+}
+
+func (c *client) giftsPage(ctx context.Context, vkID int64, offset, count int) ([]domain.Gift, int, error) {
+	var raw json.RawMessage
+	if err := c.callVK(ctx, "gifts.get", giftsParams(vkID, offset, count), &raw); err != nil {
+		return nil, 0, err
+	}
+	return decodeGiftsPage(raw)
+}
+
+func giftsParams(vkID int64, offset, count int) url.Values {
 	params := url.Values{}
 	params.Set("user_id", strconv.FormatInt(vkID, 10))
 	params.Set("offset", strconv.Itoa(offset))
 	params.Set("count", strconv.Itoa(count))
+	return params
+}
 
+func decodeGiftsPage(raw json.RawMessage) ([]domain.Gift, int, error) {
 	var resp struct {
 		Count int `json:"count"`
 		Items []struct {
@@ -189,8 +238,8 @@ func (c *client) GetGifts(ctx context.Context, vkID int64, offset, count int) ([
 		} `json:"items"`
 	}
 
-	if err := c.callVK(ctx, "gifts.get", params, &resp); err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, 0, err
 	}
 
 	gifts := make([]domain.Gift, 0, len(resp.Items))
@@ -200,98 +249,206 @@ func (c *client) GetGifts(ctx context.Context, vkID int64, offset, count int) ([
 			Text: g.Text,
 		})
 	}
-	return gifts, nil
+	return gifts, resp.Count, nil
 }
 
 func (c *client) GetFriends(ctx context.Context, vkID int64, offset, count int) ([]domain.Friend, error) {
+	friends, _, err := cachedPage(ctx, c, cacheKey(vkID, "friends.get", offset, count), c.cfg.Cache.Friends, func(ctx context.Context) ([]domain.Friend, int, error) {
+		return c.friendsPage(ctx, vkID, offset, count)
+	})
+	return friends, err
+}
+
+func (c *client) GetAllFriends(ctx context.Context, vkID int64, limit int) ([]domain.Friend, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "friends.get", "all", limit), c.cfg.Cache.Friends, func(ctx context.Context) ([]domain.Friend, int, error) {
+		return fetchAll(ctx, friendsPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.Friend, int, error) {
+			return c.friendsPage(ctx, vkID, offset, count)
+		})
+	})
+}
+
+func (c *client) friendsPage(ctx context.Context, vkID int64, offset, count int) ([]domain.Friend, int, error) {
+	var raw json.RawMessage
+	if err := c.callVK(ctx, "friends.get", friendsParams(vkID, offset, count), &raw); err != nil {
+		return nil, 0, err
+	}
+	return decodeFriendsPage(raw)
+}
+
+func friendsParams(vkID int64, offset, count int) url.Values {
 	params := url.Values{}
 	params.Set("user_id", strconv.FormatInt(vkID, 10))
 	params.Set("offset", strconv.Itoa(offset))
 	params.Set("count", strconv.Itoa(count))
-	params.Set("fields", "sex")
+	params.Set("fields", friendFields)
+	return params
+}
 
+func decodeFriendsPage(raw json.RawMessage) ([]domain.Friend, int, error) {
 	var resp struct {
-		Count int `json:"count"`
-		Items []struct {
-			ID        int64  `json:"id"`
-			FirstName string `json:"first_name"`
-			LastName  string `json:"last_name"`
-			Sex       int    `json:"sex"`
-		} `json:"items"`
+		Count int         `json:"count"`
+		Items []apiFriend `json:"items"`
 	}
 
-	if err := c.callVK(ctx, "friends.get", params, &resp); err != nil {
-		return nil, err
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, 0, err
 	}
 
 	friends := make([]domain.Friend, 0, len(resp.Items))
 	for _, f := range resp.Items {
-		friends = append(friends, domain.Friend{
-			ID:        f.ID,
-			FirstName: f.FirstName,
-			LastName:  f.LastName,
-			Sex:       f.Sex,
-		})
+		friends = append(friends, f.toDomain())
 	}
-	return friends, nil
+	return friends, resp.Count, nil
+}
+
+func (c *client) TokenStates() []TokenState {
+	return c.tokens.states()
+}
+
+func (c *client) BreakerState() gobreaker.State {
+	return c.cb.State()
 }
 
 func (c *client) callVK(ctx context.Context, method string, params url.Values, out interface{}) error {
+	vkResp, err := c.call(ctx, method, params)
+	if err != nil {
+		return err
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(vkResp.Response, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *client) call(ctx context.Context, method string, params url.Values) (*vkResponse, error) {
 	endpoint := fmt.Sprintf("%s/%s", c.cfg.BaseURL, method)
 
-	params.Set("access_token", c.cfg.AccessToken)
 	params.Set("v", c.cfg.APIVersion)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	var (
+		result  *vkResponse
+		lastErr error
+	)
+	err := c.retry.Do(ctx, func(ctx context.Context, attempt int) error {
+		resp, err := c.attempt(ctx, endpoint, params, lastErr)
+		if err != nil {
+			lastErr = err
+			if retry.IsRetryable(err) {
+				c.logger.Warn("vk request failed, retrying", zap.String("method", method), zap.Int("attempt", attempt+1), zap.Error(err))
+			}
+			return err
+		}
+		result = resp
+		return nil
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	req.URL.RawQuery = params.Encode()
-
-	operation := func() (interface{}, error) {
-		var lastErr error
-		for attempt := 0; attempt < 3; attempt++ {
-			resp, err := c.httpClient.Do(req)
-			if err != nil {
-				lastErr = err
-				c.logger.Warn("vk request error", zap.Error(err), zap.Int("attempt", attempt+1))
-				time.Sleep(time.Duration(attempt+1) * time.Second)
-				continue
-			}
+	return result, nil
+}
 
-			defer resp.Body.Close()
+// attempt picks the next available token and waits for its rate limit
+// before going through the circuit breaker, so that time queued on the
+// limiter is never seen by the breaker.
+func (c *client) attempt(ctx context.Context, endpoint string, params url.Values, lastErr error) (*vkResponse, error) {
+	token, err := c.tokens.acquire(time.Now())
+	if err != nil {
+		if lastErr != nil {
+			return nil, fmt.Errorf("%w (last error: %v)", err, lastErr)
+		}
+		return nil, err
+	}
 
-			if resp.StatusCode == http.StatusTooManyRequests {
-				c.logger.Warn("vk rate limited", zap.Int("attempt", attempt+1))
-				time.Sleep(time.Duration(attempt+1) * time.Second)
-				continue
-			}
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx, limiterKey(token)); err != nil {
+			return nil, err
+		}
+	}
 
-			if resp.StatusCode >= 400 {
-				return nil, fmt.Errorf("vk api error: status=%d", resp.StatusCode)
-			}
+	resp, err := breaker.Execute(ctx, c.cb, func() (interface{}, error) {
+		return c.send(ctx, endpoint, params, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*vkResponse), nil
+}
 
-			var vkResp vkResponse
-			if err := json.NewDecoder(resp.Body).Decode(&vkResp); err != nil {
-				return nil, err
-			}
+// send performs a single request with the given token and classifies
+// failures for the retry policy: transport errors, 429/5xx, throttling
+// (code 6) and VK internal errors (code 10) are retried with backoff, an
+// invalid token is retried right away with another one.
+func (c *client) send(ctx context.Context, endpoint string, params url.Values, token string) (*vkResponse, error) {
+	query := cloneValues(params)
+	query.Set("access_token", token)
+	req, err := newRequest(ctx, endpoint, query)
+	if err != nil {
+		return nil, err
+	}
 
-			if vkResp.Error != nil {
-				return nil, fmt.Errorf("vk error: code=%d msg=%s", vkResp.Error.ErrorCode, vkResp.Error.ErrorMsg)
-			}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, retry.Retryable(err)
+	}
+	defer resp.Body.Close()
 
-			if out != nil {
-				if err := json.Unmarshal(vkResp.Response, out); err != nil {
-					return nil, err
-				}
-			}
+	if resp.StatusCode >= 400 {
+		return nil, retry.StatusError(resp, &breaker.StatusError{Service: "vk api", StatusCode: resp.StatusCode})
+	}
 
-			return nil, nil
+	var vkResp vkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&vkResp); err != nil {
+		return nil, err
+	}
+
+	if vkResp.Error != nil {
+		apiErr := vkError(vkResp.Error.ErrorCode, vkResp.Error.ErrorMsg)
+		switch vkResp.Error.ErrorCode {
+		case errCodeTooManyRequests:
+			c.tokens.reportThrottle(token, apiErr, time.Now())
+			return nil, retry.Retryable(apiErr)
+		case errCodeInternal:
+			return nil, retry.Retryable(apiErr)
+		case errCodeAuthFailed:
+			c.tokens.reportAuthFailure(token, apiErr, time.Now())
+			return nil, retry.After(apiErr, 0)
+		default:
+			return nil, apiErr
 		}
+	}
 
-		return nil, lastErr
+	c.tokens.reportSuccess(token)
+	return &vkResp, nil
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v)+1)
+	for k, vs := range v {
+		out[k] = append([]string(nil), vs...)
 	}
+	return out
+}
 
-	_, err = c.cb.Execute(operation)
-	return err
+// Long parameter lists (bulk user IDs, execute code) do not fit into a URL,
+// VK accepts the same parameters as a form body.
+const maxQueryLen = 4096
+
+func newRequest(ctx context.Context, endpoint string, params url.Values) (*http.Request, error) {
+	encoded := params.Encode()
+	if len(encoded) <= maxQueryLen {
+		return http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+encoded, nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
 }
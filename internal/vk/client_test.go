@@ -2,15 +2,25 @@ package vk
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/sony/gobreaker"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 
 	"inteam/internal/config"
+	"inteam/internal/domain"
 )
 
 type roundTripperFunc func(*http.Request) (*http.Response, error)
@@ -25,6 +35,18 @@ func newTestHTTPClient(fn roundTripperFunc) *http.Client {
 	}
 }
 
+// newTestClient returns a client whose requests are answered by handler.
+func newTestClient(t *testing.T, handler roundTripperFunc) *client {
+	return newTestClientWithConfig(t, config.VKConfig{}, handler)
+}
+
+func newTestClientWithConfig(t *testing.T, cfg config.VKConfig, handler roundTripperFunc) *client {
+	t.Helper()
+	cfg.BaseURL = "https://api.vk.com/method"
+	cfg.APIVersion = "5.199"
+	return NewClient(cfg, newTestHTTPClient(handler), zaptest.NewLogger(t), nil).(*client)
+}
+
 func TestGetUser_Basic(t *testing.T) {
 	client := newTestHTTPClient(func(r *http.Request) (*http.Response, error) {
 		body := `{"response":[{"id":1,"screen_name":"test","first_name":"Test","last_name":"User","sex":2,"bdate":"1.1.2000","city":{"title":"City"},"about":"About"}]}`
@@ -47,3 +69,575 @@ func TestGetUser_Basic(t *testing.T) {
 	require.Equal(t, int64(1), user.ID)
 	require.Equal(t, "Test", user.FirstName)
 }
+
+func TestGetUser_ExtendedFields(t *testing.T) {
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		body := `{"response":[{"id":1,"first_name":"Test","last_name":"User","followers_count":42,` +
+			`"counters":{"photos":10,"groups":3},"online":1,"last_seen":{"time":1700000000,"platform":7},` +
+			`"university_name":"MSU","universities":[{"name":"MSU","faculty_name":"CMC","graduation":2015}],` +
+			`"career":[{"company":"Acme","position":"Engineer","from":2016}],"occupation":{"type":"work","name":"Acme"},` +
+			`"interests":"go, chess","relation":4,"personal":{"langs":["Русский","English"],"political":4,"life_main":6},` +
+			`"site":"https://example.com","verified":1}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClient(t, handler)
+	user, err := c.GetUser(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 42, user.FollowersCount)
+	require.Equal(t, 10, user.Counters.Photos)
+	require.True(t, user.Online)
+	require.Equal(t, time.Unix(1700000000, 0), user.LastSeen)
+	require.Equal(t, "MSU", user.Education.University)
+	require.Len(t, user.Universities, 1)
+	require.Equal(t, "Engineer", user.Career[0].Position)
+	require.Equal(t, 4, user.Relation)
+	require.Equal(t, []string{"Русский", "English"}, user.Personal.Langs)
+	require.Equal(t, 6, user.Personal.LifeMain)
+	require.True(t, user.Verified)
+}
+
+func TestGetAllFriends_Paginates(t *testing.T) {
+	var offsets []string
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		q := r.URL.Query()
+		offsets = append(offsets, q.Get("offset"))
+
+		var body string
+		switch q.Get("offset") {
+		case "0":
+			body = `{"response":{"count":3,"items":[{"id":1},{"id":2}]}}`
+		default:
+			body = `{"response":{"count":3,"items":[{"id":3}]}}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClient(t, handler)
+	page := func(ctx context.Context, offset, count int) ([]domain.Friend, int, error) {
+		return c.friendsPage(ctx, 1, offset, count)
+	}
+
+	friends, total, err := fetchAll(context.Background(), 2, 0, page)
+	require.NoError(t, err)
+	require.Equal(t, 3, total)
+	require.Len(t, friends, 3)
+	require.Equal(t, []string{"0", "2"}, offsets)
+
+	offsets = nil
+	friends, _, err = fetchAll(context.Background(), 2, 1, page)
+	require.NoError(t, err)
+	require.Len(t, friends, 1)
+	require.Equal(t, []string{"0"}, offsets)
+}
+
+func TestGetProfileBundle_Execute(t *testing.T) {
+	var methods []string
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		methods = append(methods, r.URL.Path)
+		body := `{"response":[` +
+			`[{"id":1,"first_name":"Test","last_name":"User"}],` +
+			`{"count":1,"items":[{"id":10,"text":"post"}]},` +
+			`false,` +
+			`{"count":2,"items":[{"id":2},{"id":3}]},` +
+			`{"count":1,"items":[{"id":5,"name":"Go","type":"group","activity":"Программирование"}]},` +
+			`{"count":2,"items":[{"id":6,"name":"News","type":"page"},{"id":7,"type":"profile"}]},` +
+			`{"count":1,"items":[{"id":8,"album_id":-6,"date":1700000000,"lat":55.75,"long":37.61,"likes":{"count":4}}]},` +
+			`{"count":1,"items":[{"id":-6,"title":"Фотографии со страницы","size":1}]}` +
+			`],"execute_errors":[{"method":"gifts.get","error_code":15,"error_msg":"Access denied"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClient(t, handler)
+	bundle, err := c.GetProfileBundle(context.Background(), 1, BundleRequest{
+		WallLimit:          100,
+		GiftsLimit:         100,
+		FriendsLimit:       100,
+		GroupsLimit:        100,
+		SubscriptionsLimit: 100,
+		PhotosLimit:        100,
+		AlbumsLimit:        100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/method/execute"}, methods)
+	require.Equal(t, "Test", bundle.User.FirstName)
+	require.Len(t, bundle.Wall, 1)
+	require.Len(t, bundle.Friends, 2)
+	require.Equal(t, "Программирование", bundle.Groups[0].Activity)
+	require.Len(t, bundle.Subscriptions, 1)
+	require.Equal(t, 4, bundle.Photos[0].Likes)
+	require.True(t, bundle.Photos[0].HasPlace())
+	require.Equal(t, "Фотографии со страницы", bundle.Albums[0].Title)
+	require.Error(t, bundle.Errors[domain.SourceGifts])
+	require.ErrorContains(t, bundle.Err(), "gifts")
+}
+
+func TestGetProfileBundle_CachesDeletedUser(t *testing.T) {
+	calls := 0
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		deleted := `{"method":"wall.get","error_code":18,"error_msg":"User was deleted or banned"}`
+		body := `{"response":[` +
+			`[{"id":1,"first_name":"DELETED","last_name":"","deactivated":"deleted"}],` +
+			`false,false,false,false,false,false,false` +
+			`],"execute_errors":[` + strings.Repeat(deleted+",", 6) + deleted + `]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		Cache: config.VKCacheConfig{Bundle: time.Minute},
+	}, handler)
+	req := BundleRequest{WallLimit: 10}
+	for i := 0; i < 2; i++ {
+		bundle, err := c.GetProfileBundle(context.Background(), 1, req)
+		require.NoError(t, err)
+		require.Nil(t, bundle.User)
+		require.ErrorIs(t, bundle.Errors[domain.SourceUser], ErrUserDeleted)
+		require.ErrorIs(t, bundle.Errors[domain.SourceWall], ErrUserDeleted)
+	}
+	require.Equal(t, 1, calls)
+}
+
+func TestProfileBundle_CachesOnlyPermanentErrors(t *testing.T) {
+	cases := map[error]bool{
+		vkError(errCodeAccessDenied, "Access denied"):        true,
+		vkError(errCodePrivateProfile, "Profile is private"): true,
+		vkError(errCodeTooManyRequests, "Too many requests"): false,
+		vkError(errCodeRateLimit, "Rate limit reached"):      false,
+		vkError(errCodeInternal, "Internal server error"):    false,
+		errors.New("connection reset"):                       false,
+	}
+	for err, want := range cases {
+		bundle := &ProfileBundle{Errors: map[domain.Source]error{domain.SourceGifts: err}}
+		require.Equal(t, want, bundle.cacheable(), err.Error())
+	}
+}
+
+func TestLocalRateLimiter_Reserve(t *testing.T) {
+	l := NewLocalRateLimiter(2, 2).(*localRateLimiter)
+	now := time.Unix(0, 0)
+
+	require.Zero(t, l.reserve("a", now))
+	require.Zero(t, l.reserve("a", now))
+	require.Equal(t, 500*time.Millisecond, l.reserve("a", now))
+	require.Zero(t, l.reserve("b", now))
+	require.Equal(t, 500*time.Millisecond, l.reserve("a", now.Add(500*time.Millisecond)))
+}
+
+func TestTokenPool_QuarantinesInvalidToken(t *testing.T) {
+	pool := newTokenPool([]string{"token-aaaa-1", "token-bbbb-2", "token-aaaa-1"}, time.Minute, 2, zaptest.NewLogger(t))
+	now := time.Unix(0, 0)
+
+	first, err := pool.acquire(now)
+	require.NoError(t, err)
+	second, err := pool.acquire(now)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+
+	pool.reportAuthFailure(first, errors.New("vk error: code=5"), now)
+	for i := 0; i < 3; i++ {
+		token, err := pool.acquire(now)
+		require.NoError(t, err)
+		require.Equal(t, second, token)
+	}
+
+	pool.reportThrottle(second, errors.New("vk error: code=6"), now)
+	_, err = pool.acquire(now)
+	require.NoError(t, err)
+	pool.reportThrottle(second, errors.New("vk error: code=6"), now)
+	_, err = pool.acquire(now)
+	require.ErrorIs(t, err, ErrNoAvailableTokens)
+
+	token, err := pool.acquire(now.Add(time.Minute))
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	states := pool.states()
+	require.Len(t, states, 2)
+	require.Equal(t, "toke...aa-1", states[0].ID)
+}
+
+func TestParseProfileRef(t *testing.T) {
+	cases := map[string]ProfileRef{
+		"1":                       {ID: 1},
+		"id1":                     {ID: 1},
+		"durov":                   {ScreenName: "durov"},
+		"@Durov":                  {ScreenName: "durov"},
+		"https://vk.com/id1":      {ID: 1},
+		"https://m.vk.com/durov?": {ScreenName: "durov"},
+		"vk.com/durov":            {ScreenName: "durov"},
+		" https://vk.ru/durov/ ":  {ScreenName: "durov"},
+	}
+	for input, want := range cases {
+		got, err := ParseProfileRef(input)
+		require.NoError(t, err, input)
+		require.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "https://example.com/durov", "a", "bad name"} {
+		_, err := ParseProfileRef(input)
+		require.ErrorIs(t, err, ErrInvalidProfileRef, input)
+	}
+}
+
+func TestDecodeWallPage_RepostsAndAttachments(t *testing.T) {
+	raw := `{"count":1,"items":[{"id":5,"owner_id":1,"from_id":1,"signer_id":0,"date":100,"edited":200,"text":"",` +
+		`"attachments":[{"type":"link","link":{"url":"https://example.com","title":"Example"}}],` +
+		`"geo":{"type":"point","coordinates":"55.7 37.6","place":{"title":"Moscow"}},` +
+		`"copy_history":[{"id":9,"owner_id":-10,"from_id":-10,"date":50,"text":"original",` +
+		`"attachments":[{"type":"photo","photo":{"id":3,"owner_id":-10,"sizes":[{"url":"s","width":10,"height":10},{"url":"x","width":100,"height":100}]}},` +
+		`{"type":"poll","poll":{"id":4,"question":"Q?","answers":[{"text":"A"},{"text":"B"}]}}]}]}]}`
+
+	posts, total, err := decodeWallPage([]byte(raw))
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	p := posts[0]
+	require.True(t, p.IsRepost())
+	require.Equal(t, time.Unix(200, 0), p.Edited)
+	require.Equal(t, "https://example.com", p.Attachments[0].URL)
+	require.Equal(t, "Moscow", p.Geo.Place)
+	require.Equal(t, int64(-10), p.CopyHistory[0].OwnerID)
+	require.Equal(t, "x", p.CopyHistory[0].Attachments[0].URL)
+	require.Equal(t, []string{"A", "B"}, p.CopyHistory[0].Attachments[1].PollAnswers)
+}
+
+func TestGetComments_ExpandsThreads(t *testing.T) {
+	const threadLen = 150
+	reply := func(id int) apiComment { return apiComment{ID: int64(1000 + id), FromID: 5} }
+
+	var replyPages []string
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		q := r.URL.Query()
+		offset, _ := strconv.Atoi(q.Get("offset"))
+		count, _ := strconv.Atoi(q.Get("count"))
+
+		var resp struct {
+			Count             int          `json:"count"`
+			CurrentLevelCount int          `json:"current_level_count"`
+			Items             []apiComment `json:"items"`
+		}
+		if q.Get("comment_id") == "" {
+			resp.Count, resp.CurrentLevelCount = threadLen+2, 2
+			if offset == 0 {
+				first := apiComment{ID: 1, FromID: 2}
+				first.Thread.Count = threadLen
+				for i := 0; i < threadItemsPreview; i++ {
+					first.Thread.Items = append(first.Thread.Items, reply(i))
+				}
+				resp.Items = []apiComment{first, {ID: 2, FromID: 3}}
+			}
+		} else {
+			replyPages = append(replyPages, q.Get("offset")+"/"+q.Get("count"))
+			resp.Count = threadLen
+			for i := offset; i < min(offset+count, threadLen); i++ {
+				resp.Items = append(resp.Items, reply(i))
+			}
+		}
+
+		body, err := json.Marshal(map[string]interface{}{"response": resp})
+		require.NoError(t, err)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(string(body))),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClient(t, handler)
+	comments, err := c.GetComments(context.Background(), 1, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, comments, threadLen+2)
+	require.Equal(t, []string{"0/100", "100/50"}, replyPages)
+	require.Equal(t, int64(1), comments[0].ID)
+	for i, comment := range comments[1 : threadLen+1] {
+		require.Equal(t, int64(1000+i), comment.ID)
+		require.Equal(t, int64(1), comment.ParentID)
+	}
+	require.Equal(t, int64(2), comments[threadLen+1].ID)
+	require.Zero(t, comments[threadLen+1].ParentID)
+
+	replyPages = nil
+	comments, err = c.GetComments(context.Background(), 1, 10, 60)
+	require.NoError(t, err)
+	require.Len(t, comments, 60)
+	require.Equal(t, []string{"0/59"}, replyPages)
+	require.Equal(t, int64(1058), comments[59].ID)
+}
+
+func TestGetMutualFriends_BatchesTargets(t *testing.T) {
+	var codes []string
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		codes = append(codes, r.URL.Query().Get("code"))
+		body := `{"response":[[{"id":2,"common_friends":[3]},{"id":3,"common_friends":[2]}],false],` +
+			`"execute_errors":[{"method":"friends.getMutual","error_code":30,"error_msg":"This profile is private"}]}`
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClient(t, handler)
+
+	targets := make([]int64, 150)
+	for i := range targets {
+		targets[i] = int64(i + 2)
+	}
+
+	mutual, err := c.GetMutualFriends(context.Background(), 1, targets)
+	require.NoError(t, err)
+	require.Len(t, codes, 1)
+	require.Equal(t, 2, strings.Count(codes[0], "API.friends.getMutual"))
+	require.Equal(t, []int64{3}, mutual[2])
+	require.Equal(t, []int64{2}, mutual[3])
+}
+
+func TestGetUsers_ChunksAndReportsMissing(t *testing.T) {
+	var (
+		methods []string
+		chunks  []int
+	)
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		require.NoError(t, r.ParseForm())
+		methods = append(methods, r.Method)
+		ids := strings.Split(r.Form.Get("user_ids"), ",")
+		chunks = append(chunks, len(ids))
+
+		body := `{"response":[]}`
+		if ids[0] == "1" {
+			body = `{"response":[{"id":1,"first_name":"Test"},{"id":2,"first_name":"DELETED","deactivated":"deleted"}]}`
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		Cache: config.VKCacheConfig{Users: time.Minute},
+	}, handler)
+
+	ids := make([]int64, 1200)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	res, err := c.GetUsers(context.Background(), ids, "")
+	require.NoError(t, err)
+	require.Equal(t, []int{1000, 200}, chunks)
+	require.Equal(t, []string{http.MethodPost, http.MethodGet}, methods)
+	require.Len(t, res.Users, 1)
+	require.Equal(t, []int64{2}, res.Deactivated)
+	require.Len(t, res.Missing, 1198)
+
+	// Returned users are cached for GetUser.
+	methods = nil
+	user, err := c.GetUser(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "Test", user.FirstName)
+	require.Empty(t, methods)
+}
+
+func TestGetUser_CachesNotFoundAndCoalesces(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		<-release
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"response":[]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		Cache: config.VKCacheConfig{Users: time.Minute, NegativeTTL: time.Minute},
+	}, handler)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetUser(context.Background(), 1)
+			require.ErrorIs(t, err, ErrNotFound)
+		}()
+	}
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	_, err := c.GetUser(context.Background(), 1)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetUser_SharedFetchOutlivesFirstCaller(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"response":[{"id":1,"first_name":"Test","last_name":"User"}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		OperationTimeout: time.Second,
+		Cache:            config.VKCacheConfig{Users: time.Minute, NegativeTTL: time.Minute},
+	}, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetUser(ctx, 1)
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan error, 1)
+	go func() {
+		user, err := c.GetUser(context.Background(), 1)
+		if err == nil && user.ID != 1 {
+			err = errors.New("unexpected user")
+		}
+		second <- err
+	}()
+	time.Sleep(10 * time.Millisecond) // let the second caller join the fetch
+
+	cancel()
+	require.ErrorIs(t, <-first, context.Canceled)
+	close(release)
+	require.NoError(t, <-second)
+
+	_, err := c.GetUser(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestDetach_KeepsCallerDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	detached, stop := detach(ctx, time.Second)
+	defer stop()
+
+	cancel()
+	require.NoError(t, detached.Err())
+	got, ok := detached.Deadline()
+	require.True(t, ok)
+	require.Equal(t, deadline, got)
+
+	detached, stop = detach(context.Background(), time.Second)
+	defer stop()
+	got, ok = detached.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Second), got, 100*time.Millisecond)
+}
+
+func TestCall_RetriesTransientErrors(t *testing.T) {
+	responses := []string{
+		`{"error":{"error_code":10,"error_msg":"Internal server error"}}`,
+		`{"error":{"error_code":6,"error_msg":"Too many requests per second"}}`,
+		`{"response":[{"id":1,"first_name":"Test","last_name":"User"}]}`,
+	}
+	var calls atomic.Int32
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		body := responses[calls.Add(1)-1]
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		ThrottleThreshold: 5,
+		Retry:             config.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		Breaker:           config.BreakerConfig{FailureThreshold: 5},
+	}, handler)
+
+	user, err := c.GetUser(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), user.ID)
+	require.Equal(t, int32(3), calls.Load())
+
+	// Permanent errors are returned right away.
+	responses = append(responses, `{"error":{"error_code":18,"error_msg":"User was deleted or banned"}}`)
+	_, err = c.GetUser(context.Background(), 2)
+	require.ErrorIs(t, err, ErrUserDeleted)
+	require.Equal(t, int32(4), calls.Load())
+}
+
+func TestCall_BreakerIgnoresRequestErrors(t *testing.T) {
+	body := `{"error":{"error_code":18,"error_msg":"User was deleted or banned"}}`
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Header:     make(http.Header),
+		}, nil
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		Breaker: config.BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute},
+	}, handler)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GetUser(context.Background(), 1)
+		require.ErrorIs(t, err, ErrUserDeleted)
+	}
+	require.Equal(t, gobreaker.StateClosed, c.BreakerState())
+
+	body = `{"error":{"error_code":10,"error_msg":"Internal server error"}}`
+	for i := 0; i < 2; i++ {
+		_, err := c.GetUser(context.Background(), 1)
+		require.Error(t, err)
+	}
+	require.Equal(t, gobreaker.StateOpen, c.BreakerState())
+
+	_, err := c.GetUser(context.Background(), 1)
+	require.ErrorIs(t, err, gobreaker.ErrOpenState)
+}
+
+func TestCall_BreakerIgnoresCallerDeadline(t *testing.T) {
+	handler := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+
+	c := newTestClientWithConfig(t, config.VKConfig{
+		Breaker: config.BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute},
+	}, handler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.call(ctx, "users.get", url.Values{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, gobreaker.StateClosed, c.BreakerState())
+}
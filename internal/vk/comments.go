@@ -0,0 +1,127 @@
+package vk
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"inteam/internal/domain"
+)
+
+const (
+	commentsPageSize   = 100
+	threadItemsPreview = 10
+)
+
+type apiComment struct {
+	ID             int64  `json:"id"`
+	FromID         int64  `json:"from_id"`
+	Date           int64  `json:"date"`
+	Text           string `json:"text"`
+	ReplyToUser    int64  `json:"reply_to_user"`
+	ReplyToComment int64  `json:"reply_to_comment"`
+	Likes          struct {
+		Count int `json:"count"`
+	} `json:"likes"`
+	Thread struct {
+		Count int          `json:"count"`
+		Items []apiComment `json:"items"`
+	} `json:"thread"`
+}
+
+func (c apiComment) toDomain(ownerID, postID, parentID int64) domain.Comment {
+	return domain.Comment{
+		ID:             c.ID,
+		OwnerID:        ownerID,
+		PostID:         postID,
+		ParentID:       parentID,
+		FromID:         c.FromID,
+		Date:           time.Unix(c.Date, 0),
+		Text:           c.Text,
+		Likes:          c.Likes.Count,
+		ReplyToUser:    c.ReplyToUser,
+		ReplyToComment: c.ReplyToComment,
+	}
+}
+
+// GetComments returns up to limit comments of a wall post including replies
+// in threads. Thread previews returned with the top-level page are completed
+// with extra requests when a thread is longer than the preview.
+func (c *client) GetComments(ctx context.Context, ownerID, postID int64, limit int) ([]domain.Comment, error) {
+	return cached(ctx, c, cacheKey(ownerID, "wall.getComments", postID, limit), c.cfg.Cache.Comments, func(ctx context.Context) ([]domain.Comment, error) {
+		return c.fetchComments(ctx, ownerID, postID, limit)
+	})
+}
+
+func (c *client) fetchComments(ctx context.Context, ownerID, postID int64, limit int) ([]domain.Comment, error) {
+	var comments []domain.Comment
+	full := func() bool { return limit > 0 && len(comments) >= limit }
+
+	top, _, err := fetchAll(ctx, commentsPageSize, limit, func(ctx context.Context, offset, count int) ([]apiComment, int, error) {
+		return c.commentsPage(ctx, ownerID, postID, 0, offset, count)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tc := range top {
+		if full() {
+			break
+		}
+		comments = append(comments, tc.toDomain(ownerID, postID, 0))
+
+		replies, want := tc.Thread.Items, tc.Thread.Count
+		if limit > 0 {
+			want = min(want, limit-len(comments))
+		}
+		if want > len(replies) {
+			replies, _, err = fetchAll(ctx, commentsPageSize, want, func(ctx context.Context, offset, count int) ([]apiComment, int, error) {
+				return c.commentsPage(ctx, ownerID, postID, tc.ID, offset, count)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, reply := range replies {
+			if full() {
+				break
+			}
+			comments = append(comments, reply.toDomain(ownerID, postID, tc.ID))
+		}
+	}
+
+	return comments, nil
+}
+
+func (c *client) commentsPage(ctx context.Context, ownerID, postID, commentID int64, offset, count int) ([]apiComment, int, error) {
+	params := url.Values{}
+	params.Set("owner_id", strconv.FormatInt(ownerID, 10))
+	params.Set("post_id", strconv.FormatInt(postID, 10))
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("count", strconv.Itoa(count))
+	params.Set("sort", "asc")
+	params.Set("need_likes", "1")
+	if commentID != 0 {
+		params.Set("comment_id", strconv.FormatInt(commentID, 10))
+	} else {
+		params.Set("thread_items_count", strconv.Itoa(threadItemsPreview))
+	}
+
+	var resp struct {
+		Count             int          `json:"count"`
+		CurrentLevelCount int          `json:"current_level_count"`
+		Items             []apiComment `json:"items"`
+	}
+	if err := c.callVK(ctx, "wall.getComments", params, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	// count includes replies in threads, pagination walks the current level.
+	total := resp.Count
+	if resp.CurrentLevelCount > 0 {
+		total = resp.CurrentLevelCount
+	}
+	return resp.Items, total, nil
+}
@@ -0,0 +1,84 @@
+package vk
+
+import (
+	"errors"
+	"fmt"
+
+	"inteam/internal/breaker"
+)
+
+var (
+	ErrUserDeleted     = errors.New("vk: user deleted or banned")
+	ErrProfilePrivate  = errors.New("vk: profile is private")
+	ErrAccessDenied    = errors.New("vk: access denied")
+	ErrTooManyRequests = errors.New("vk: too many requests")
+	ErrInvalidToken    = errors.New("vk: invalid access token")
+	ErrNotFound        = errors.New("vk: not found")
+)
+
+// VK API error codes, see https://dev.vk.com/ru/reference/errors.
+const (
+	errCodeAuthFailed       = 5
+	errCodeTooManyRequests  = 6
+	errCodePermissionDenied = 7
+	errCodeFloodControl     = 9
+	errCodeInternal         = 10
+	errCodeAccessDenied     = 15
+	errCodeUserDeleted      = 18
+	errCodeRateLimit        = 29
+	errCodePrivateProfile   = 30
+	errCodeNotFound         = 104
+	errCodeInvalidUserID    = 113
+	errCodeAlbumDenied      = 200
+	errCodeGroupDenied      = 203
+	errCodeGroupAccess      = 260
+)
+
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("vk error: code=%d msg=%s", e.Code, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.Code {
+	case errCodeAuthFailed:
+		return ErrInvalidToken
+	case errCodeTooManyRequests, errCodeFloodControl, errCodeRateLimit:
+		return ErrTooManyRequests
+	case errCodePermissionDenied, errCodeAccessDenied, errCodeAlbumDenied, errCodeGroupDenied, errCodeGroupAccess:
+		return ErrAccessDenied
+	case errCodeUserDeleted:
+		return ErrUserDeleted
+	case errCodePrivateProfile:
+		return ErrProfilePrivate
+	case errCodeNotFound, errCodeInvalidUserID:
+		return ErrNotFound
+	default:
+		return nil
+	}
+}
+
+// isUpstreamFailure reports whether err should count against the breaker.
+func isUpstreamFailure(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case errCodeTooManyRequests, errCodeFloodControl, errCodeInternal, errCodeRateLimit:
+			return true
+		default:
+			return false
+		}
+	}
+	if errors.Is(err, ErrNoAvailableTokens) {
+		return false
+	}
+	return breaker.IsUpstreamFailure(err)
+}
+
+func vkError(code int, msg string) error {
+	return &APIError{Code: code, Message: msg}
+}
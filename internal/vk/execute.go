@@ -0,0 +1,422 @@
+package vk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"inteam/internal/domain"
+)
+
+// VK allows at most 25 API calls inside a single execute request.
+const maxExecuteCalls = 25
+
+type BundleRequest struct {
+	WallLimit          int
+	GiftsLimit         int
+	FriendsLimit       int
+	GroupsLimit        int
+	SubscriptionsLimit int
+	PhotosLimit        int
+	AlbumsLimit        int
+}
+
+type ProfileBundle struct {
+	User          *domain.VKUser
+	Wall          []domain.WallPost
+	Gifts         []domain.Gift
+	Friends       []domain.Friend
+	Groups        []domain.Community
+	Subscriptions []domain.Community
+	Photos        []domain.Photo
+	Albums        []domain.Album
+	WallTotal     int
+	GiftsTotal    int
+	FriendsTotal  int
+	GroupsTotal   int
+	PhotosTotal   int
+	AlbumsTotal   int
+	Errors        map[domain.Source]error
+}
+
+// Err returns the first per-source error in a stable order.
+func (b *ProfileBundle) Err() error {
+	for _, source := range []domain.Source{
+		domain.SourceUser,
+		domain.SourceWall,
+		domain.SourceGifts,
+		domain.SourceFriends,
+		domain.SourceGroups,
+		domain.SourceSubscriptions,
+		domain.SourcePhotos,
+		domain.SourceAlbums,
+	} {
+		if err := b.Errors[source]; err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// cacheable reports whether every per-source error is a permanent access error.
+func (b *ProfileBundle) cacheable() bool {
+	for _, err := range b.Errors {
+		switch {
+		case errors.Is(err, ErrAccessDenied), errors.Is(err, ErrProfilePrivate),
+			errors.Is(err, ErrUserDeleted), errors.Is(err, ErrNotFound):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// bundleError is the cached form of a per-source error: either a VK API
+// error or the kind of a sentinel such as a deleted user.
+type bundleError struct {
+	Code    int    `json:",omitempty"`
+	Message string `json:",omitempty"`
+	Kind    string `json:",omitempty"`
+}
+
+func (b *ProfileBundle) MarshalJSON() ([]byte, error) {
+	type plain ProfileBundle
+	errs := make(map[domain.Source]bundleError, len(b.Errors))
+	for source, err := range b.Errors {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			errs[source] = bundleError{Code: apiErr.Code, Message: apiErr.Message}
+			continue
+		}
+		for kind, sentinel := range negativeKinds {
+			if errors.Is(err, sentinel) {
+				errs[source] = bundleError{Kind: kind}
+				break
+			}
+		}
+	}
+	return json.Marshal(struct {
+		*plain
+		Errors map[domain.Source]bundleError
+	}{(*plain)(b), errs})
+}
+
+func (b *ProfileBundle) UnmarshalJSON(data []byte) error {
+	type plain ProfileBundle
+	aux := struct {
+		*plain
+		Errors map[domain.Source]bundleError
+	}{plain: (*plain)(b)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	b.Errors = make(map[domain.Source]error, len(aux.Errors))
+	for source, e := range aux.Errors {
+		if e.Kind == "" {
+			b.Errors[source] = vkError(e.Code, e.Message)
+			continue
+		}
+		sentinel, ok := negativeKinds[e.Kind]
+		if !ok {
+			return fmt.Errorf("vk: unknown cached error kind %q", e.Kind)
+		}
+		b.Errors[source] = fmt.Errorf("%w (cached)", sentinel)
+	}
+	return nil
+}
+
+type executeCall struct {
+	method string
+	params url.Values
+}
+
+type executeError struct {
+	Method    string `json:"method"`
+	ErrorCode int    `json:"error_code"`
+	ErrorMsg  string `json:"error_msg"`
+}
+
+type pagedSource struct {
+	source   domain.Source
+	method   string
+	pageSize int
+	limit    int
+	params   func(offset, count int) url.Values
+	decode   func(raw json.RawMessage) (int, error)
+}
+
+func (p *pagedSource) pageCount(offset int) int {
+	count := p.pageSize
+	if p.limit > 0 && p.limit-offset < count {
+		count = p.limit - offset
+	}
+	return count
+}
+
+func (p *pagedSource) call(offset int) executeCall {
+	return executeCall{method: p.method, params: p.params(offset, p.pageCount(offset))}
+}
+
+func (c *client) GetProfileBundle(ctx context.Context, vkID int64, req BundleRequest) (*ProfileBundle, error) {
+	key := cacheKey(vkID, "execute.bundle",
+		req.WallLimit, req.GiftsLimit, req.FriendsLimit, req.GroupsLimit,
+		req.SubscriptionsLimit, req.PhotosLimit, req.AlbumsLimit)
+	return cached(ctx, c, key, c.cfg.Cache.Bundle, func(ctx context.Context) (*ProfileBundle, error) {
+		return c.fetchProfileBundle(ctx, vkID, req)
+	})
+}
+
+func (c *client) fetchProfileBundle(ctx context.Context, vkID int64, req BundleRequest) (*ProfileBundle, error) {
+	bundle := &ProfileBundle{Errors: make(map[domain.Source]error)}
+
+	sources := []*pagedSource{
+		{
+			source:   domain.SourceWall,
+			method:   "wall.get",
+			pageSize: wallPageSize,
+			limit:    req.WallLimit,
+			params:   func(offset, count int) url.Values { return wallParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				posts, total, err := decodeWallPage(raw)
+				bundle.Wall = append(bundle.Wall, posts...)
+				bundle.WallTotal = total
+				return total, err
+			},
+		},
+		{
+			source:   domain.SourceGifts,
+			method:   "gifts.get",
+			pageSize: giftsPageSize,
+			limit:    req.GiftsLimit,
+			params:   func(offset, count int) url.Values { return giftsParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				gifts, total, err := decodeGiftsPage(raw)
+				bundle.Gifts = append(bundle.Gifts, gifts...)
+				bundle.GiftsTotal = total
+				return total, err
+			},
+		},
+		{
+			source:   domain.SourceFriends,
+			method:   "friends.get",
+			pageSize: friendsPageSize,
+			limit:    req.FriendsLimit,
+			params:   func(offset, count int) url.Values { return friendsParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				friends, total, err := decodeFriendsPage(raw)
+				bundle.Friends = append(bundle.Friends, friends...)
+				bundle.FriendsTotal = total
+				return total, err
+			},
+		},
+		{
+			source:   domain.SourceGroups,
+			method:   "groups.get",
+			pageSize: groupsPageSize,
+			limit:    req.GroupsLimit,
+			params:   func(offset, count int) url.Values { return groupsParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				groups, total, err := decodeCommunityPage(raw)
+				bundle.Groups = append(bundle.Groups, groups...)
+				bundle.GroupsTotal = total
+				return total, err
+			},
+		},
+		{
+			source:   domain.SourceSubscriptions,
+			method:   "users.getSubscriptions",
+			pageSize: subscriptionsPageSize,
+			limit:    req.SubscriptionsLimit,
+			params:   func(offset, count int) url.Values { return subscriptionsParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				subs, total, err := decodeCommunityPage(raw)
+				bundle.Subscriptions = append(bundle.Subscriptions, subs...)
+				return total, err
+			},
+		},
+		{
+			source:   domain.SourcePhotos,
+			method:   "photos.getAll",
+			pageSize: photosPageSize,
+			limit:    req.PhotosLimit,
+			params:   func(offset, count int) url.Values { return photosParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				photos, total, err := decodePhotosPage(raw)
+				bundle.Photos = append(bundle.Photos, photos...)
+				bundle.PhotosTotal = total
+				return total, err
+			},
+		},
+		{
+			source:   domain.SourceAlbums,
+			method:   "photos.getAlbums",
+			pageSize: albumsPageSize,
+			limit:    req.AlbumsLimit,
+			params:   func(offset, count int) url.Values { return albumsParams(vkID, offset, count) },
+			decode: func(raw json.RawMessage) (int, error) {
+				albums, total, err := decodeAlbumsPage(raw)
+				bundle.Albums = append(bundle.Albums, albums...)
+				bundle.AlbumsTotal = total
+				return total, err
+			},
+		},
+	}
+
+	first := []executeCall{{method: "users.get", params: userParams(vkID)}}
+	for _, src := range sources {
+		first = append(first, src.call(0))
+	}
+
+	results, errs := c.executeOrFallback(ctx, first)
+
+	if errs[0] != nil {
+		bundle.Errors[domain.SourceUser] = errs[0]
+	} else if user, err := decodeUser(results[0]); err != nil {
+		bundle.Errors[domain.SourceUser] = err
+	} else {
+		bundle.User = user
+		c.storeUser(ctx, user)
+	}
+
+	var (
+		pending []executeCall
+		owners  []*pagedSource
+	)
+	for i, src := range sources {
+		if err := errs[i+1]; err != nil {
+			bundle.Errors[src.source] = err
+			continue
+		}
+		total, err := src.decode(results[i+1])
+		if err != nil {
+			bundle.Errors[src.source] = err
+			continue
+		}
+
+		for offset := src.pageCount(0); offset < total && (src.limit <= 0 || offset < src.limit); offset += src.pageSize {
+			pending = append(pending, src.call(offset))
+			owners = append(owners, src)
+		}
+	}
+
+	for start := 0; start < len(pending); start += maxExecuteCalls {
+		end := min(start+maxExecuteCalls, len(pending))
+		results, errs := c.executeOrFallback(ctx, pending[start:end])
+		for i, raw := range results {
+			src := owners[start+i]
+			if bundle.Errors[src.source] != nil {
+				continue
+			}
+			if errs[i] != nil {
+				bundle.Errors[src.source] = errs[i]
+				continue
+			}
+			if _, err := src.decode(raw); err != nil {
+				bundle.Errors[src.source] = err
+			}
+		}
+	}
+
+	return bundle, nil
+}
+
+// executeOrFallback runs calls as a single execute request and falls back to
+// issuing them one by one when the execute request itself fails.
+func (c *client) executeOrFallback(ctx context.Context, calls []executeCall) ([]json.RawMessage, []error) {
+	results, errs, err := c.execute(ctx, calls)
+	if err == nil {
+		return results, errs
+	}
+
+	c.logger.Warn("vk execute failed, falling back to individual calls", zap.Error(err), zap.Int("calls", len(calls)))
+
+	results = make([]json.RawMessage, len(calls))
+	errs = make([]error, len(calls))
+	for i, call := range calls {
+		var raw json.RawMessage
+		if err := c.callVK(ctx, call.method, call.params, &raw); err != nil {
+			errs[i] = err
+			continue
+		}
+		results[i] = raw
+	}
+	return results, errs
+}
+
+func (c *client) execute(ctx context.Context, calls []executeCall) ([]json.RawMessage, []error, error) {
+	code, err := buildExecuteCode(calls)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	params := url.Values{}
+	params.Set("code", code)
+
+	vkResp, err := c.call(ctx, "execute", params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var results []json.RawMessage
+	if err := json.Unmarshal(vkResp.Response, &results); err != nil {
+		return nil, nil, fmt.Errorf("decode execute response: %w", err)
+	}
+	if len(results) != len(calls) {
+		return nil, nil, fmt.Errorf("execute returned %d results for %d calls", len(results), len(calls))
+	}
+
+	// Failed calls yield false in their slot; execute_errors lists the
+	// failures in call order.
+	errs := make([]error, len(calls))
+	next := 0
+	for i, raw := range results {
+		if !bytes.Equal(bytes.TrimSpace(raw), []byte("false")) {
+			continue
+		}
+		if next < len(vkResp.ExecuteErrors) {
+			e := vkResp.ExecuteErrors[next]
+			errs[i] = vkError(e.ErrorCode, e.ErrorMsg)
+			next++
+		} else {
+			errs[i] = fmt.Errorf("vk execute: %s failed", calls[i].method)
+		}
+	}
+
+	return results, errs, nil
+}
+
+func buildExecuteCode(calls []executeCall) (string, error) {
+	var b strings.Builder
+	b.WriteString("return [")
+	for i, call := range calls {
+		args := make(map[string]interface{}, len(call.params))
+		for key := range call.params {
+			value := call.params.Get(key)
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				args[key] = n
+			} else {
+				args[key] = value
+			}
+		}
+
+		encoded, err := json.Marshal(args)
+		if err != nil {
+			return "", err
+		}
+
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "API.%s(%s)", call.method, encoded)
+	}
+	b.WriteString("];")
+	return b.String(), nil
+}
@@ -0,0 +1,154 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"inteam/internal/domain"
+)
+
+const (
+	groupsPageSize        = 1000
+	subscriptionsPageSize = 200
+	communityFields       = "activity,description,members_count"
+	communityInfoFields   = communityFields + ",status,site,verified,city,start_date"
+)
+
+type apiCommunity struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	ScreenName   string `json:"screen_name"`
+	Type         string `json:"type"`
+	Activity     string `json:"activity"`
+	Description  string `json:"description"`
+	MembersCount int    `json:"members_count"`
+	IsClosed     int    `json:"is_closed"`
+	Deactivated  string `json:"deactivated"`
+	Status       string `json:"status"`
+	Site         string `json:"site"`
+	Verified     int    `json:"verified"`
+	City         struct {
+		Title string `json:"title"`
+	} `json:"city"`
+}
+
+func (g apiCommunity) toDomain() domain.Community {
+	return domain.Community{
+		ID:           g.ID,
+		Name:         g.Name,
+		ScreenName:   g.ScreenName,
+		Type:         g.Type,
+		Activity:     g.Activity,
+		Description:  g.Description,
+		MembersCount: g.MembersCount,
+		IsClosed:     g.IsClosed != 0,
+		Status:       g.Status,
+		Site:         g.Site,
+		Verified:     g.Verified == 1,
+		City:         g.City.Title,
+	}
+}
+
+// GetCommunity returns a group, public page or event by its positive ID.
+func (c *client) GetCommunity(ctx context.Context, groupID int64) (*domain.Community, error) {
+	return cached(ctx, c, cacheKey(-groupID, "groups.getById"), c.cfg.Cache.Communities, func(ctx context.Context) (*domain.Community, error) {
+		return c.fetchCommunity(ctx, groupID)
+	})
+}
+
+func (c *client) fetchCommunity(ctx context.Context, groupID int64) (*domain.Community, error) {
+	params := url.Values{}
+	params.Set("group_id", strconv.FormatInt(groupID, 10))
+	params.Set("fields", communityInfoFields)
+
+	var raw json.RawMessage
+	if err := c.callVK(ctx, "groups.getById", params, &raw); err != nil {
+		return nil, err
+	}
+
+	// Since API 5.194 the groups are wrapped into an object; older versions
+	// return a bare array.
+	var groups []apiCommunity
+	if err := json.Unmarshal(raw, &groups); err != nil {
+		var wrapped struct {
+			Groups []apiCommunity `json:"groups"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil {
+			return nil, err
+		}
+		groups = wrapped.Groups
+	}
+
+	if len(groups) == 0 {
+		return nil, ErrNotFound
+	}
+	g := groups[0]
+	if g.Deactivated != "" {
+		return nil, fmt.Errorf("%w: community %s", ErrNotFound, g.Deactivated)
+	}
+	community := g.toDomain()
+	return &community, nil
+}
+
+func (c *client) GetGroups(ctx context.Context, vkID int64, limit int) ([]domain.Community, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "groups.get", limit), c.cfg.Cache.Groups, func(ctx context.Context) ([]domain.Community, int, error) {
+		return fetchAll(ctx, groupsPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.Community, int, error) {
+			var raw json.RawMessage
+			if err := c.callVK(ctx, "groups.get", groupsParams(vkID, offset, count), &raw); err != nil {
+				return nil, 0, err
+			}
+			return decodeCommunityPage(raw)
+		})
+	})
+}
+
+func (c *client) GetSubscriptions(ctx context.Context, vkID int64, limit int) ([]domain.Community, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "users.getSubscriptions", limit), c.cfg.Cache.Subscriptions, func(ctx context.Context) ([]domain.Community, int, error) {
+		return fetchAll(ctx, subscriptionsPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.Community, int, error) {
+			var raw json.RawMessage
+			if err := c.callVK(ctx, "users.getSubscriptions", subscriptionsParams(vkID, offset, count), &raw); err != nil {
+				return nil, 0, err
+			}
+			return decodeCommunityPage(raw)
+		})
+	})
+}
+
+func groupsParams(vkID int64, offset, count int) url.Values {
+	params := url.Values{}
+	params.Set("user_id", strconv.FormatInt(vkID, 10))
+	params.Set("extended", "1")
+	params.Set("fields", communityFields)
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("count", strconv.Itoa(count))
+	return params
+}
+
+func subscriptionsParams(vkID int64, offset, count int) url.Values {
+	return groupsParams(vkID, offset, count)
+}
+
+// decodeCommunityPage decodes groups.get and users.getSubscriptions pages.
+// Subscriptions may also contain followed people, which are skipped.
+func decodeCommunityPage(raw json.RawMessage) ([]domain.Community, int, error) {
+	var resp struct {
+		Count int            `json:"count"`
+		Items []apiCommunity `json:"items"`
+	}
+
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	communities := make([]domain.Community, 0, len(resp.Items))
+	for _, g := range resp.Items {
+		if g.Type == "profile" {
+			continue
+		}
+		communities = append(communities, g.toDomain())
+	}
+	return communities, resp.Count, nil
+}
@@ -0,0 +1,129 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+const (
+	// friends.getMutual accepts up to 100 target_uids per call.
+	mutualTargetsPerCall = 100
+	friendIDsPerUser     = 5000
+)
+
+// GetMutualFriends returns, for every target, the friends it has in common
+// with sourceID. Targets are batched via target_uids and the batches are sent
+// through execute; targets with hidden friend lists are simply absent.
+func (c *client) GetMutualFriends(ctx context.Context, sourceID int64, targetIDs []int64) (map[int64][]int64, error) {
+	ttl := c.cfg.Cache.Mutual
+	key := func(id int64) string { return cacheKey(sourceID, "friends.getMutual", id) }
+	mutual, pending := cachedByID[[]int64](ctx, c, targetIDs, ttl, key)
+
+	var calls []executeCall
+	for start := 0; start < len(pending); start += mutualTargetsPerCall {
+		end := min(start+mutualTargetsPerCall, len(pending))
+		calls = append(calls, executeCall{method: "friends.getMutual", params: mutualParams(sourceID, pending[start:end])})
+	}
+
+	fetched := make(map[int64][]int64, len(pending))
+	err := c.executeBatches(ctx, calls, func(_ int, raw json.RawMessage) error {
+		var items []struct {
+			ID            int64   `json:"id"`
+			CommonFriends []int64 `json:"common_friends"`
+		}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return err
+		}
+		for _, item := range items {
+			fetched[item.ID] = item.CommonFriends
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, common := range fetched {
+		mutual[id] = common
+		if ttl > 0 {
+			c.storeValue(ctx, key(id), common, ttl)
+		}
+	}
+	return mutual, nil
+}
+
+// GetFriendIDs returns up to limit friend IDs of every user. Users whose
+// friend lists are private or who are deactivated are skipped.
+func (c *client) GetFriendIDs(ctx context.Context, userIDs []int64, limit int) (map[int64][]int64, error) {
+	if limit <= 0 || limit > friendIDsPerUser {
+		limit = friendIDsPerUser
+	}
+
+	ttl := c.cfg.Cache.Friends
+	key := func(id int64) string { return cacheKey(id, "friends.get", "ids", limit) }
+	lists, pending := cachedByID[[]int64](ctx, c, userIDs, ttl, key)
+
+	calls := make([]executeCall, 0, len(pending))
+	for _, id := range pending {
+		params := url.Values{}
+		params.Set("user_id", strconv.FormatInt(id, 10))
+		params.Set("count", strconv.Itoa(limit))
+		calls = append(calls, executeCall{method: "friends.get", params: params})
+	}
+
+	err := c.executeBatches(ctx, calls, func(i int, raw json.RawMessage) error {
+		var resp struct {
+			Items []int64 `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return err
+		}
+		lists[pending[i]] = resp.Items
+		if ttl > 0 {
+			c.storeValue(ctx, key(pending[i]), resp.Items, ttl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// executeBatches sends calls in execute batches and hands every successful
+// result to decode together with its index in calls. Calls rejected because
+// of private or deleted profiles are skipped, any other failure aborts.
+func (c *client) executeBatches(ctx context.Context, calls []executeCall, decode func(i int, raw json.RawMessage) error) error {
+	for start := 0; start < len(calls); start += maxExecuteCalls {
+		end := min(start+maxExecuteCalls, len(calls))
+		results, errs := c.executeOrFallback(ctx, calls[start:end])
+		for i, raw := range results {
+			if err := errs[i]; err != nil {
+				if !isRestricted(err) {
+					return err
+				}
+				continue
+			}
+			if err := decode(start+i, raw); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isRestricted(err error) bool {
+	return errors.Is(err, ErrProfilePrivate) ||
+		errors.Is(err, ErrAccessDenied) ||
+		errors.Is(err, ErrUserDeleted)
+}
+
+func mutualParams(sourceID int64, targetIDs []int64) url.Values {
+	params := url.Values{}
+	params.Set("source_uid", strconv.FormatInt(sourceID, 10))
+	params.Set("target_uids", joinIDs(targetIDs))
+	return params
+}
@@ -0,0 +1,53 @@
+package vk
+
+import "context"
+
+// Page size caps accepted by the corresponding VK API methods.
+const (
+	wallPageSize    = 100
+	giftsPageSize   = 100
+	friendsPageSize = 5000
+)
+
+type pageFunc[T any] func(ctx context.Context, offset, count int) ([]T, int, error)
+
+// fetchAll walks offset page by page until VK's reported total is exhausted
+// or limit items are collected. A non-positive limit means no ceiling.
+// It returns the collected items together with the total reported by VK.
+func fetchAll[T any](ctx context.Context, pageSize, limit int, fetch pageFunc[T]) ([]T, int, error) {
+	var (
+		items  []T
+		total  int
+		offset int
+	)
+
+	for {
+		count := pageSize
+		if limit > 0 && limit-len(items) < count {
+			count = limit - len(items)
+		}
+
+		page, pageTotal, err := fetch(ctx, offset, count)
+		if err != nil {
+			return nil, 0, err
+		}
+		total = pageTotal
+		items = append(items, page...)
+		offset += count
+
+		if len(page) == 0 || offset >= total {
+			break
+		}
+		if limit > 0 && len(items) >= limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+	return items, total, nil
+}
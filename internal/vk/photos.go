@@ -0,0 +1,155 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"inteam/internal/domain"
+)
+
+const (
+	photosPageSize = 200
+	albumsPageSize = 100
+)
+
+type apiPhoto struct {
+	ID      int64   `json:"id"`
+	AlbumID int64   `json:"album_id"`
+	OwnerID int64   `json:"owner_id"`
+	Date    int64   `json:"date"`
+	Text    string  `json:"text"`
+	Lat     float64 `json:"lat"`
+	Long    float64 `json:"long"`
+	Likes   struct {
+		Count int `json:"count"`
+	} `json:"likes"`
+	Comments struct {
+		Count int `json:"count"`
+	} `json:"comments"`
+	Reposts struct {
+		Count int `json:"count"`
+	} `json:"reposts"`
+}
+
+func (p apiPhoto) toDomain() domain.Photo {
+	return domain.Photo{
+		ID:       p.ID,
+		AlbumID:  p.AlbumID,
+		OwnerID:  p.OwnerID,
+		Date:     time.Unix(p.Date, 0),
+		Text:     p.Text,
+		Likes:    p.Likes.Count,
+		Comments: p.Comments.Count,
+		Reposts:  p.Reposts.Count,
+		Lat:      p.Lat,
+		Long:     p.Long,
+	}
+}
+
+type apiAlbum struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Size        int    `json:"size"`
+	Created     int64  `json:"created"`
+	Updated     int64  `json:"updated"`
+}
+
+func (a apiAlbum) toDomain() domain.Album {
+	album := domain.Album{
+		ID:          a.ID,
+		Title:       a.Title,
+		Description: a.Description,
+		Size:        a.Size,
+	}
+	// System albums (profile, wall, saved photos) have no dates.
+	if a.Created > 0 {
+		album.Created = time.Unix(a.Created, 0)
+	}
+	if a.Updated > 0 {
+		album.Updated = time.Unix(a.Updated, 0)
+	}
+	return album
+}
+
+// GetPhotos returns photo metadata from all albums of the user, newest first.
+// Images themselves are never downloaded.
+func (c *client) GetPhotos(ctx context.Context, vkID int64, limit int) ([]domain.Photo, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "photos.getAll", limit), c.cfg.Cache.Photos, func(ctx context.Context) ([]domain.Photo, int, error) {
+		return fetchAll(ctx, photosPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.Photo, int, error) {
+			var raw json.RawMessage
+			if err := c.callVK(ctx, "photos.getAll", photosParams(vkID, offset, count), &raw); err != nil {
+				return nil, 0, err
+			}
+			return decodePhotosPage(raw)
+		})
+	})
+}
+
+// GetAlbums returns the user's albums including system ones.
+func (c *client) GetAlbums(ctx context.Context, vkID int64, limit int) ([]domain.Album, int, error) {
+	return cachedPage(ctx, c, cacheKey(vkID, "photos.getAlbums", limit), c.cfg.Cache.Photos, func(ctx context.Context) ([]domain.Album, int, error) {
+		return fetchAll(ctx, albumsPageSize, limit, func(ctx context.Context, offset, count int) ([]domain.Album, int, error) {
+			var raw json.RawMessage
+			if err := c.callVK(ctx, "photos.getAlbums", albumsParams(vkID, offset, count), &raw); err != nil {
+				return nil, 0, err
+			}
+			return decodeAlbumsPage(raw)
+		})
+	})
+}
+
+func photosParams(vkID int64, offset, count int) url.Values {
+	params := url.Values{}
+	params.Set("owner_id", strconv.FormatInt(vkID, 10))
+	params.Set("extended", "1")
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("count", strconv.Itoa(count))
+	return params
+}
+
+func albumsParams(vkID int64, offset, count int) url.Values {
+	params := url.Values{}
+	params.Set("owner_id", strconv.FormatInt(vkID, 10))
+	params.Set("need_system", "1")
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("count", strconv.Itoa(count))
+	return params
+}
+
+func decodePhotosPage(raw json.RawMessage) ([]domain.Photo, int, error) {
+	var resp struct {
+		Count int        `json:"count"`
+		Items []apiPhoto `json:"items"`
+	}
+
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	photos := make([]domain.Photo, 0, len(resp.Items))
+	for _, p := range resp.Items {
+		photos = append(photos, p.toDomain())
+	}
+	return photos, resp.Count, nil
+}
+
+func decodeAlbumsPage(raw json.RawMessage) ([]domain.Album, int, error) {
+	var resp struct {
+		Count int        `json:"count"`
+		Items []apiAlbum `json:"items"`
+	}
+
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, 0, err
+	}
+
+	albums := make([]domain.Album, 0, len(resp.Items))
+	for _, a := range resp.Items {
+		albums = append(albums, a.toDomain())
+	}
+	return albums, resp.Count, nil
+}
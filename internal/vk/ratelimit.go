@@ -0,0 +1,146 @@
+package vk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+type RateLimiter interface {
+	Wait(ctx context.Context, key string) error
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type localRateLimiter struct {
+	rate    float64
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewLocalRateLimiter(rps float64, burst int) RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &localRateLimiter{
+		rate:    rps,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *localRateLimiter) Wait(ctx context.Context, key string) error {
+	return sleepCtx(ctx, l.reserve(key, time.Now()))
+}
+
+// reserve takes a token from the bucket and returns how long the caller has
+// to wait before the token becomes valid.
+func (l *localRateLimiter) reserve(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+// The bucket lives in a Redis hash and is refilled using the server clock so
+// that every replica sees the same state. The script returns the wait time in
+// microseconds.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + (now - ts) * rate / 1000000) - 1
+local wait = 0
+if tokens < 0 then
+  wait = math.ceil(-tokens * 1000000 / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return wait
+`)
+
+type redisRateLimiter struct {
+	redis    redis.UniversalClient
+	rate     float64
+	burst    int
+	fallback RateLimiter
+	logger   *zap.Logger
+}
+
+func NewRedisRateLimiter(redisClient redis.UniversalClient, rps float64, burst int, logger *zap.Logger) RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &redisRateLimiter{
+		redis:    redisClient,
+		rate:     rps,
+		burst:    burst,
+		fallback: NewLocalRateLimiter(rps, burst),
+		logger:   logger,
+	}
+}
+
+func (l *redisRateLimiter) Wait(ctx context.Context, key string) error {
+	wait, err := tokenBucketScript.Run(ctx, l.redis, []string{"vk:ratelimit:" + key}, l.rate, l.burst).Int64()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		l.logger.Warn("redis rate limiter unavailable, using local limiter", zap.Error(err))
+		return l.fallback.Wait(ctx, key)
+	}
+	return sleepCtx(ctx, time.Duration(wait)*time.Microsecond)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// limiterKey identifies a token without storing it in plain text.
+func limiterKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,137 @@
+package vk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidProfileRef = errors.New("vk: invalid profile reference")
+
+var (
+	screenNameRe = regexp.MustCompile(`^[a-zA-Z0-9_.]{2,64}$`)
+	idRefRe      = regexp.MustCompile(`^id(\d+)$`)
+)
+
+var vkHosts = map[string]struct{}{
+	"vk.com":     {},
+	"www.vk.com": {},
+	"m.vk.com":   {},
+	"vk.ru":      {},
+	"www.vk.ru":  {},
+	"m.vk.ru":    {},
+}
+
+// ProfileRef is a parsed user reference: either a numeric ID or a screen name
+// that still has to be resolved.
+type ProfileRef struct {
+	ID         int64
+	ScreenName string
+}
+
+// ParseProfileRef accepts numeric IDs, "id123", screen names (optionally
+// prefixed with "@") and vk.com profile URLs.
+func ParseProfileRef(input string) (ProfileRef, error) {
+	ref := strings.TrimSpace(input)
+	ref = strings.TrimPrefix(ref, "@")
+
+	lower := strings.ToLower(ref)
+	if strings.Contains(lower, "://") || hasVKHostPrefix(lower) {
+		if !strings.Contains(ref, "://") {
+			ref = "https://" + ref
+		}
+		u, err := url.Parse(ref)
+		if err != nil {
+			return ProfileRef{}, fmt.Errorf("%w: %s", ErrInvalidProfileRef, input)
+		}
+		if _, ok := vkHosts[strings.ToLower(u.Hostname())]; !ok {
+			return ProfileRef{}, fmt.Errorf("%w: unsupported host %q", ErrInvalidProfileRef, u.Hostname())
+		}
+		ref = strings.Trim(u.Path, "/")
+		if i := strings.Index(ref, "/"); i >= 0 {
+			ref = ref[:i]
+		}
+	}
+
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil && id > 0 {
+		return ProfileRef{ID: id}, nil
+	}
+	if m := idRefRe.FindStringSubmatch(ref); m != nil {
+		if id, err := strconv.ParseInt(m[1], 10, 64); err == nil && id > 0 {
+			return ProfileRef{ID: id}, nil
+		}
+	}
+	if screenNameRe.MatchString(ref) {
+		return ProfileRef{ScreenName: strings.ToLower(ref)}, nil
+	}
+
+	return ProfileRef{}, fmt.Errorf("%w: %s", ErrInvalidProfileRef, input)
+}
+
+func hasVKHostPrefix(ref string) bool {
+	for host := range vkHosts {
+		if strings.HasPrefix(ref, host+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *client) ResolveScreenName(ctx context.Context, screenName string) (int64, error) {
+	screenName = strings.ToLower(screenName)
+
+	return cached(ctx, c, "vk:screen_name:"+screenName, c.cfg.Cache.ScreenNames, func(ctx context.Context) (int64, error) {
+		id, err := c.resolveScreenName(ctx, screenName)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			c.logger.Warn("utils.resolveScreenName failed, falling back to users.get", zap.Error(err))
+			id, err = c.resolveViaUsersGet(ctx, screenName)
+		}
+		return id, err
+	})
+}
+
+func (c *client) resolveScreenName(ctx context.Context, screenName string) (int64, error) {
+	params := url.Values{}
+	params.Set("screen_name", screenName)
+
+	var raw json.RawMessage
+	if err := c.callVK(ctx, "utils.resolveScreenName", params, &raw); err != nil {
+		return 0, err
+	}
+
+	// An unknown screen name yields an empty array instead of an object.
+	var resp struct {
+		Type     string `json:"type"`
+		ObjectID int64  `json:"object_id"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil || resp.ObjectID == 0 {
+		return 0, fmt.Errorf("%w: screen name %q", ErrNotFound, screenName)
+	}
+	if resp.Type != "user" {
+		return 0, fmt.Errorf("%w: %q is a %s, not a user", ErrNotFound, screenName, resp.Type)
+	}
+	return resp.ObjectID, nil
+}
+
+func (c *client) resolveViaUsersGet(ctx context.Context, screenName string) (int64, error) {
+	params := url.Values{}
+	params.Set("user_ids", screenName)
+
+	var raw json.RawMessage
+	if err := c.callVK(ctx, "users.get", params, &raw); err != nil {
+		return 0, err
+	}
+
+	user, err := decodeUser(raw)
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
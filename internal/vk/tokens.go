@@ -0,0 +1,188 @@
+package vk
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrNoAvailableTokens = errors.New("vk: no available access tokens")
+
+type TokenStatus string
+
+const (
+	TokenActive    TokenStatus = "active"
+	TokenThrottled TokenStatus = "throttled"
+	TokenInvalid   TokenStatus = "invalid"
+)
+
+type TokenState struct {
+	ID               string      `json:"id"`
+	Status           TokenStatus `json:"status"`
+	Requests         int64       `json:"requests"`
+	Failures         int64       `json:"failures"`
+	QuarantinedUntil *time.Time  `json:"quarantined_until,omitempty"`
+	LastError        string      `json:"last_error,omitempty"`
+}
+
+type tokenEntry struct {
+	token     string
+	status    TokenStatus
+	requests  int64
+	failures  int64
+	throttles int
+	until     time.Time
+	lastError string
+}
+
+type tokenPool struct {
+	mu                sync.Mutex
+	entries           []*tokenEntry
+	next              int
+	quarantine        time.Duration
+	throttleThreshold int
+	logger            *zap.Logger
+}
+
+func newTokenPool(tokens []string, quarantine time.Duration, throttleThreshold int, logger *zap.Logger) *tokenPool {
+	if throttleThreshold < 1 {
+		throttleThreshold = 1
+	}
+
+	p := &tokenPool{
+		quarantine:        quarantine,
+		throttleThreshold: throttleThreshold,
+		logger:            logger,
+	}
+
+	seen := make(map[string]struct{}, len(tokens))
+	for _, t := range tokens {
+		if t == "" {
+			continue
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		p.entries = append(p.entries, &tokenEntry{token: t, status: TokenActive})
+	}
+	return p
+}
+
+// acquire returns the next usable token in round-robin order. Quarantined
+// tokens are skipped until their quarantine expires. An empty pool yields an
+// empty token and leaves it to VK to reject the request.
+func (p *tokenPool) acquire(now time.Time) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.entries) == 0 {
+		return "", nil
+	}
+
+	for i := 0; i < len(p.entries); i++ {
+		e := p.entries[(p.next+i)%len(p.entries)]
+		if e.status != TokenActive {
+			if now.Before(e.until) {
+				continue
+			}
+			e.status = TokenActive
+			e.throttles = 0
+		}
+
+		p.next = (p.next + i + 1) % len(p.entries)
+		e.requests++
+		return e.token, nil
+	}
+
+	return "", ErrNoAvailableTokens
+}
+
+func (p *tokenPool) reportSuccess(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e := p.find(token); e != nil {
+		e.throttles = 0
+	}
+}
+
+func (p *tokenPool) reportAuthFailure(token string, err error, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.find(token)
+	if e == nil {
+		return
+	}
+	e.failures++
+	e.lastError = err.Error()
+	p.quarantineLocked(e, TokenInvalid, now)
+}
+
+func (p *tokenPool) reportThrottle(token string, err error, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := p.find(token)
+	if e == nil {
+		return
+	}
+	e.failures++
+	e.throttles++
+	e.lastError = err.Error()
+	if e.throttles >= p.throttleThreshold {
+		p.quarantineLocked(e, TokenThrottled, now)
+	}
+}
+
+func (p *tokenPool) quarantineLocked(e *tokenEntry, status TokenStatus, now time.Time) {
+	e.status = status
+	e.until = now.Add(p.quarantine)
+	p.logger.Warn("vk token quarantined",
+		zap.String("token", maskToken(e.token)),
+		zap.String("status", string(status)),
+		zap.Time("until", e.until),
+		zap.String("last_error", e.lastError),
+	)
+}
+
+func (p *tokenPool) states() []TokenState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := make([]TokenState, 0, len(p.entries))
+	for _, e := range p.entries {
+		state := TokenState{
+			ID:        maskToken(e.token),
+			Status:    e.status,
+			Requests:  e.requests,
+			Failures:  e.failures,
+			LastError: e.lastError,
+		}
+		if e.status != TokenActive {
+			until := e.until
+			state.QuarantinedUntil = &until
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+func (p *tokenPool) find(token string) *tokenEntry {
+	for _, e := range p.entries {
+		if e.token == token {
+			return e
+		}
+	}
+	return nil
+}
+
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	return token[:4] + "..." + token[len(token)-4:]
+}
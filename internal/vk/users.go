@@ -0,0 +1,176 @@
+package vk
+
+import (
+	"time"
+
+	"inteam/internal/domain"
+)
+
+const userFields = "bdate,city,about,sex,screen_name,followers_count,counters,last_seen,online," +
+	"education,universities,career,occupation,interests,activities,books,movies,music," +
+	"relation,personal,site,verified"
+
+type apiUser struct {
+	ID         int64  `json:"id"`
+	ScreenName string `json:"screen_name"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Sex        int    `json:"sex"`
+	BDate      string `json:"bdate"`
+	City       struct {
+		Title string `json:"title"`
+	} `json:"city"`
+	About          string `json:"about"`
+	Deactivated    string `json:"deactivated"`
+	FollowersCount int    `json:"followers_count"`
+	Counters       struct {
+		Photos int `json:"photos"`
+		Videos int `json:"videos"`
+		Groups int `json:"groups"`
+		Audios int `json:"audios"`
+	} `json:"counters"`
+	Online   int `json:"online"`
+	LastSeen struct {
+		Time     int64 `json:"time"`
+		Platform int   `json:"platform"`
+	} `json:"last_seen"`
+	UniversityName string `json:"university_name"`
+	FacultyName    string `json:"faculty_name"`
+	Graduation     int    `json:"graduation"`
+	Universities   []struct {
+		Name        string `json:"name"`
+		FacultyName string `json:"faculty_name"`
+		ChairName   string `json:"chair_name"`
+		Graduation  int    `json:"graduation"`
+	} `json:"universities"`
+	Career []struct {
+		GroupID  int64  `json:"group_id"`
+		Company  string `json:"company"`
+		Position string `json:"position"`
+		From     int    `json:"from"`
+		Until    int    `json:"until"`
+	} `json:"career"`
+	Occupation struct {
+		Type string `json:"type"`
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"occupation"`
+	Interests  string `json:"interests"`
+	Activities string `json:"activities"`
+	Books      string `json:"books"`
+	Movies     string `json:"movies"`
+	Music      string `json:"music"`
+	Relation   int    `json:"relation"`
+	Personal   struct {
+		Langs     []string `json:"langs"`
+		Political int      `json:"political"`
+		LifeMain  int      `json:"life_main"`
+	} `json:"personal"`
+	Site     string `json:"site"`
+	Verified int    `json:"verified"`
+}
+
+func (u apiUser) toDomain() *domain.VKUser {
+	user := &domain.VKUser{
+		ID:             u.ID,
+		ScreenName:     u.ScreenName,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		Sex:            u.Sex,
+		BirthDate:      u.BDate,
+		City:           u.City.Title,
+		About:          u.About,
+		FollowersCount: u.FollowersCount,
+		Counters: domain.VKCounters{
+			Photos: u.Counters.Photos,
+			Videos: u.Counters.Videos,
+			Groups: u.Counters.Groups,
+			Audios: u.Counters.Audios,
+		},
+		Online:           u.Online == 1,
+		LastSeenPlatform: u.LastSeen.Platform,
+		Education: domain.VKEducation{
+			University: u.UniversityName,
+			Faculty:    u.FacultyName,
+			Graduation: u.Graduation,
+		},
+		Occupation: domain.VKOccupation{
+			Type: u.Occupation.Type,
+			ID:   u.Occupation.ID,
+			Name: u.Occupation.Name,
+		},
+		Interests:  u.Interests,
+		Activities: u.Activities,
+		Books:      u.Books,
+		Movies:     u.Movies,
+		Music:      u.Music,
+		Relation:   u.Relation,
+		Personal: domain.VKPersonal{
+			Langs:     u.Personal.Langs,
+			Political: u.Personal.Political,
+			LifeMain:  u.Personal.LifeMain,
+		},
+		Site:     u.Site,
+		Verified: u.Verified == 1,
+	}
+
+	if u.LastSeen.Time > 0 {
+		user.LastSeen = time.Unix(u.LastSeen.Time, 0)
+	}
+
+	for _, uni := range u.Universities {
+		user.Universities = append(user.Universities, domain.VKUniversity{
+			Name:       uni.Name,
+			Faculty:    uni.FacultyName,
+			Chair:      uni.ChairName,
+			Graduation: uni.Graduation,
+		})
+	}
+
+	for _, job := range u.Career {
+		user.Career = append(user.Career, domain.VKCareer{
+			GroupID:  job.GroupID,
+			Company:  job.Company,
+			Position: job.Position,
+			From:     job.From,
+			Until:    job.Until,
+		})
+	}
+
+	return user
+}
+
+const friendFields = "sex,city,bdate,last_seen"
+
+// apiFriend is the friends.get item; deactivated is returned regardless of
+// the requested fields.
+type apiFriend struct {
+	ID          int64  `json:"id"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Sex         int    `json:"sex"`
+	BDate       string `json:"bdate"`
+	Deactivated string `json:"deactivated"`
+	City        struct {
+		Title string `json:"title"`
+	} `json:"city"`
+	LastSeen struct {
+		Time int64 `json:"time"`
+	} `json:"last_seen"`
+}
+
+func (f apiFriend) toDomain() domain.Friend {
+	friend := domain.Friend{
+		ID:          f.ID,
+		FirstName:   f.FirstName,
+		LastName:    f.LastName,
+		Sex:         f.Sex,
+		City:        f.City.Title,
+		BirthDate:   f.BDate,
+		Deactivated: f.Deactivated,
+	}
+	if f.LastSeen.Time > 0 {
+		friend.LastSeen = time.Unix(f.LastSeen.Time, 0)
+	}
+	return friend
+}
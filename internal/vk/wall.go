@@ -0,0 +1,176 @@
+package vk
+
+import (
+	"time"
+
+	"inteam/internal/domain"
+)
+
+type apiWallPost struct {
+	ID       int64  `json:"id"`
+	OwnerID  int64  `json:"owner_id"`
+	FromID   int64  `json:"from_id"`
+	SignerID int64  `json:"signer_id"`
+	Date     int64  `json:"date"`
+	Edited   int64  `json:"edited"`
+	Text     string `json:"text"`
+	Likes    struct {
+		Count int `json:"count"`
+	} `json:"likes"`
+	Reposts struct {
+		Count int `json:"count"`
+	} `json:"reposts"`
+	Comments struct {
+		Count int `json:"count"`
+	} `json:"comments"`
+	Views struct {
+		Count int `json:"count"`
+	} `json:"views"`
+	PostType    string          `json:"post_type"`
+	IsPinned    int             `json:"is_pinned"`
+	Attachments []apiAttachment `json:"attachments"`
+	CopyHistory []apiWallPost   `json:"copy_history"`
+	Geo         *struct {
+		Type        string `json:"type"`
+		Coordinates string `json:"coordinates"`
+		Place       struct {
+			Title string `json:"title"`
+			City  string `json:"city"`
+		} `json:"place"`
+	} `json:"geo"`
+}
+
+type apiAttachment struct {
+	Type  string `json:"type"`
+	Photo *struct {
+		ID      int64  `json:"id"`
+		OwnerID int64  `json:"owner_id"`
+		Text    string `json:"text"`
+		Sizes   []struct {
+			URL    string `json:"url"`
+			Width  int    `json:"width"`
+			Height int    `json:"height"`
+		} `json:"sizes"`
+	} `json:"photo"`
+	Video *struct {
+		ID          int64  `json:"id"`
+		OwnerID     int64  `json:"owner_id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		Duration    int    `json:"duration"`
+	} `json:"video"`
+	Link *struct {
+		URL         string `json:"url"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	} `json:"link"`
+	Audio *struct {
+		ID       int64  `json:"id"`
+		OwnerID  int64  `json:"owner_id"`
+		Artist   string `json:"artist"`
+		Title    string `json:"title"`
+		Duration int    `json:"duration"`
+	} `json:"audio"`
+	Poll *struct {
+		ID       int64  `json:"id"`
+		OwnerID  int64  `json:"owner_id"`
+		Question string `json:"question"`
+		Answers  []struct {
+			Text string `json:"text"`
+		} `json:"answers"`
+	} `json:"poll"`
+	Doc *struct {
+		ID      int64  `json:"id"`
+		OwnerID int64  `json:"owner_id"`
+		Title   string `json:"title"`
+		Ext     string `json:"ext"`
+		URL     string `json:"url"`
+		Size    int64  `json:"size"`
+	} `json:"doc"`
+}
+
+func (p apiWallPost) toDomain() domain.WallPost {
+	post := domain.WallPost{
+		ID:          p.ID,
+		OwnerID:     p.OwnerID,
+		FromID:      p.FromID,
+		SignerID:    p.SignerID,
+		Date:        time.Unix(p.Date, 0),
+		Text:        p.Text,
+		Likes:       p.Likes.Count,
+		Reposts:     p.Reposts.Count,
+		Comments:    p.Comments.Count,
+		Views:       p.Views.Count,
+		PostType:    p.PostType,
+		IsPinned:    p.IsPinned == 1,
+		Attachments: decodeAttachments(p.Attachments),
+	}
+
+	if p.Edited > 0 {
+		post.Edited = time.Unix(p.Edited, 0)
+	}
+
+	if p.Geo != nil {
+		post.Geo = &domain.Geo{
+			Type:        p.Geo.Type,
+			Coordinates: p.Geo.Coordinates,
+			Place:       p.Geo.Place.Title,
+			City:        p.Geo.Place.City,
+		}
+	}
+
+	for _, orig := range p.CopyHistory {
+		post.CopyHistory = append(post.CopyHistory, domain.RepostedPost{
+			ID:          orig.ID,
+			OwnerID:     orig.OwnerID,
+			FromID:      orig.FromID,
+			Date:        time.Unix(orig.Date, 0),
+			Text:        orig.Text,
+			Attachments: decodeAttachments(orig.Attachments),
+		})
+	}
+
+	return post
+}
+
+func decodeAttachments(items []apiAttachment) []domain.Attachment {
+	if len(items) == 0 {
+		return nil
+	}
+
+	attachments := make([]domain.Attachment, 0, len(items))
+	for _, a := range items {
+		att := domain.Attachment{Type: a.Type}
+
+		switch {
+		case a.Photo != nil:
+			att.ID, att.OwnerID, att.Text = a.Photo.ID, a.Photo.OwnerID, a.Photo.Text
+			best := 0
+			for _, size := range a.Photo.Sizes {
+				if area := size.Width * size.Height; area >= best {
+					best = area
+					att.URL = size.URL
+				}
+			}
+		case a.Video != nil:
+			att.ID, att.OwnerID = a.Video.ID, a.Video.OwnerID
+			att.Title, att.Description, att.Duration = a.Video.Title, a.Video.Description, a.Video.Duration
+		case a.Link != nil:
+			att.URL, att.Title, att.Description = a.Link.URL, a.Link.Title, a.Link.Description
+		case a.Audio != nil:
+			att.ID, att.OwnerID = a.Audio.ID, a.Audio.OwnerID
+			att.Artist, att.Title, att.Duration = a.Audio.Artist, a.Audio.Title, a.Audio.Duration
+		case a.Poll != nil:
+			att.ID, att.OwnerID, att.Title = a.Poll.ID, a.Poll.OwnerID, a.Poll.Question
+			for _, answer := range a.Poll.Answers {
+				att.PollAnswers = append(att.PollAnswers, answer.Text)
+			}
+		case a.Doc != nil:
+			att.ID, att.OwnerID = a.Doc.ID, a.Doc.OwnerID
+			att.Title, att.URL, att.Ext, att.Size = a.Doc.Title, a.Doc.URL, a.Doc.Ext, a.Doc.Size
+		}
+
+		attachments = append(attachments, att)
+	}
+	return attachments
+}
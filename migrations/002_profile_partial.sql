@@ -0,0 +1,3 @@
+-- Profiles analyzed with some sections closed by privacy settings
+
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS partial BOOLEAN NOT NULL DEFAULT false;
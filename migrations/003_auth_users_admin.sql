@@ -0,0 +1,3 @@
+-- Admin flag for operational endpoints (cache purge / warm)
+
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+-- Prompt template the profile summary was generated with
+
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(64);
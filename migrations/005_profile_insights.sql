@@ -0,0 +1,3 @@
+-- Structured insights extracted by the language model, stored as JSON
+
+ALTER TABLE profiles ADD COLUMN IF NOT EXISTS insights JSONB;